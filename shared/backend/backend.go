@@ -0,0 +1,93 @@
+package backend
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/uyuni-project/uyuni-tools/shared/kubernetes"
+	"github.com/uyuni-project/uyuni-tools/shared/podman"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// Auto is the backend value to use for automatic detection.
+const Auto = "auto"
+
+// backends lists the available backends in the order of preference for the detection.
+// Adding a new backend only requires to add it here.
+var backends = []func(globalFlags *types.GlobalFlags) types.Backend{
+	podman.NewBackend,
+	kubernetes.NewBackend,
+}
+
+// Names returns the names of the available backends.
+func Names() []string {
+	names := []string{}
+	for _, newBackend := range backends {
+		names = append(names, newBackend(&types.GlobalFlags{}).Name())
+	}
+	return names
+}
+
+// Get returns the backend selected with the --backend flag, the backend configuration key
+// or the UYUNI_BACKEND environment variable.
+// If none is selected or the value is auto, the first usable backend is returned.
+func Get(globalFlags *types.GlobalFlags) (types.Backend, error) {
+	name := globalFlags.Backend
+	if name == "" || name == Auto {
+		return detect(globalFlags)
+	}
+
+	for _, newBackend := range backends {
+		backend := newBackend(globalFlags)
+		if backend.Name() != name {
+			continue
+		}
+		if err := backend.Probe(); err != nil {
+			return nil, fmt.Errorf("selected %s backend cannot be used: %w", name, err)
+		}
+		if globalFlags.Verbose {
+			log.Printf("Using %s backend as selected by the user\n", name)
+		}
+		return backend, nil
+	}
+	return nil, &utils.ConfigError{
+		Message: fmt.Sprintf("unknown backend %s, possible values: %s, %s", name, strings.Join(Names(), ", "), Auto),
+	}
+}
+
+// detect returns the first usable backend and reports why the other ones have been rejected.
+func detect(globalFlags *types.GlobalFlags) (types.Backend, error) {
+	var selected types.Backend
+	rejected := []string{}
+	alternatives := []string{}
+
+	for _, newBackend := range backends {
+		backend := newBackend(globalFlags)
+		if err := backend.Probe(); err != nil {
+			rejected = append(rejected, fmt.Sprintf("%s: %s", backend.Name(), err))
+		} else if selected == nil {
+			selected = backend
+		} else {
+			alternatives = append(alternatives, backend.Name())
+		}
+	}
+
+	if selected == nil {
+		return nil, fmt.Errorf("no usable backend found:\n  %s", strings.Join(rejected, "\n  "))
+	}
+
+	if globalFlags.Verbose {
+		for _, reason := range rejected {
+			log.Printf("Backend rejected: %s\n", reason)
+		}
+	}
+	if len(alternatives) > 0 {
+		log.Printf("Using %s backend, %s also usable: use --backend to select another one\n",
+			selected.Name(), strings.Join(alternatives, ", "))
+	} else if globalFlags.Verbose {
+		log.Printf("Using %s backend, the only usable one\n", selected.Name())
+	}
+	return selected, nil
+}
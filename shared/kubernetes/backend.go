@@ -0,0 +1,305 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Name is the identifier of the kubernetes backend.
+const Name = "kubernetes"
+
+// DefaultNamespace is the namespace of the server when none is configured.
+const DefaultNamespace = "default"
+
+type kubernetesBackend struct {
+	namespace string
+	context   string
+	timeouts  types.Timeouts
+}
+
+// NewBackend returns the backend managing the server on a kubernetes cluster using client-go, kubectl and helm.
+// The server is looked for in the namespace and kubeconfig context of the global flags.
+func NewBackend(globalFlags *types.GlobalFlags) types.Backend {
+	namespace := globalFlags.Namespace
+	if namespace == "" {
+		namespace = DefaultNamespace
+	}
+	return &kubernetesBackend{
+		namespace: namespace,
+		context:   globalFlags.KubeContext,
+		timeouts:  globalFlags.Timeouts.WithDefaults(),
+	}
+}
+
+func (b *kubernetesBackend) Name() string {
+	return Name
+}
+
+func (b *kubernetesBackend) Probe() error {
+	if _, err := exec.LookPath("kubectl"); err != nil {
+		return err
+	}
+	setKubeContext(b.context)
+	// Only check the configuration without connecting to the cluster
+	if _, err := getRestConfig(); err != nil {
+		return fmt.Errorf("not configured to connect to a cluster: %w", err)
+	}
+	return nil
+}
+
+// GetPodName returns the name of the server pod in namespace.
+// A running pod is preferred over the ones being started or terminated.
+// A utils.NotRunningError is returned if no server pod can be found.
+func GetPodName(namespace string) (string, error) {
+	client, err := getClientset()
+	if err != nil {
+		return "", &utils.NotRunningError{Name: namespace, Backend: Name, Err: err}
+	}
+	pods, err := client.CoreV1().Pods(namespace).List(context.Background(), metav1.ListOptions{LabelSelector: "app=uyuni"})
+	if err != nil {
+		return "", &utils.NotRunningError{Name: namespace, Backend: Name, Err: err}
+	}
+
+	podName := ""
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		if pod.Status.Phase == corev1.PodRunning {
+			return pod.Name, nil
+		}
+		if podName == "" {
+			podName = pod.Name
+		}
+	}
+	if podName == "" {
+		return "", &utils.NotRunningError{Name: namespace, Backend: Name}
+	}
+	return podName, nil
+}
+
+func (b *kubernetesBackend) Check(viper *viper.Viper, fqdn string) []types.CheckResult {
+	return checkKubernetes(b.namespace, fqdn)
+}
+
+func (b *kubernetesBackend) Exec(globalFlags *types.GlobalFlags, interactive bool, tty bool, env []string, args ...string) error {
+	podName, err := GetPodName(b.namespace)
+	if err != nil {
+		return err
+	}
+
+	commandArgs := []string{"exec", "-n", b.namespace}
+	if interactive {
+		commandArgs = append(commandArgs, "-i")
+	}
+	if tty {
+		commandArgs = append(commandArgs, "-t")
+	}
+	commandArgs = append(commandArgs, podName, "-c", "uyuni", "--")
+	commandArgs = append(commandArgs, utils.GetShellArgs(env, args)...)
+
+	return utils.RunInteractiveCmd("kubectl", withKubectlContext(commandArgs))
+}
+
+func (b *kubernetesBackend) Command(args ...string) (*exec.Cmd, error) {
+	podName, err := GetPodName(b.namespace)
+	if err != nil {
+		return nil, err
+	}
+	podArgs := []string{"exec", "-i", "-n", b.namespace, podName, "-c", "uyuni", "--"}
+	return kubectlCommand(append(podArgs, args...)...), nil
+}
+
+func (b *kubernetesBackend) Copy(globalFlags *types.GlobalFlags, src string, dst string, user string, group string) error {
+	podName, err := GetPodName(b.namespace)
+	if err != nil {
+		return err
+	}
+	srcExpanded, dstExpanded := utils.GetCopyPaths(podName, src, dst)
+	commandArgs := []string{"cp", "-n", b.namespace, "-c", "uyuni", srcExpanded, dstExpanded}
+	if err := utils.RunCmd("kubectl", withKubectlContext(commandArgs), "failed to copy file"); err != nil {
+		return err
+	}
+
+	if chownArgs := utils.GetChownArgs(dst, user, group); len(chownArgs) > 0 {
+		execArgs := append([]string{"exec", "-n", b.namespace, podName, "-c", "uyuni", "--"}, chownArgs...)
+		return utils.RunCmd("kubectl", withKubectlContext(execArgs), "failed to change file owner")
+	}
+	return nil
+}
+
+func (b *kubernetesBackend) Logs(globalFlags *types.GlobalFlags, options types.LogsOptions) error {
+	podName, err := GetPodName(b.namespace)
+	if err != nil {
+		return err
+	}
+	args := []string{"logs", "-n", b.namespace, "-c", "uyuni"}
+	if options.Follow {
+		args = append(args, "-f")
+	}
+	if !options.Since.IsZero() {
+		args = append(args, "--since-time", options.Since.Format(time.RFC3339))
+	}
+	if options.Tail >= 0 {
+		args = append(args, "--tail", strconv.Itoa(options.Tail))
+	}
+	args = append(args, podName)
+	return utils.RunInteractiveCmd("kubectl", withKubectlContext(args))
+}
+
+func (b *kubernetesBackend) GetImage() (string, error) {
+	client, err := getClientset()
+	if err != nil {
+		return "", err
+	}
+	deployment, err := client.AppsV1().Deployments(b.namespace).Get(context.Background(), HELM_APP_NAME, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get the image of the %s deployment: %w", HELM_APP_NAME, err)
+	}
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		if container.Name == "uyuni" {
+			return container.Image, nil
+		}
+	}
+	return "", fmt.Errorf("no uyuni container in the %s deployment", HELM_APP_NAME)
+}
+
+func (b *kubernetesBackend) GetImageRelease(viper *viper.Viper, image string) (string, error) {
+	cmd := kubectlCommand("run", "uyuni-release-check", "-n", b.namespace,
+		"--rm", "-i", "--restart=Never", "--image="+image, "--command", "--",
+		"sh", "-c", utils.ReleaseCommand)
+	out, err := utils.GetRunner().Output(cmd)
+	if err != nil {
+		return "", utils.NewCmdError(fmt.Sprintf("failed to get the server version in image %s", image), cmd, nil, err)
+	}
+	return string(out), nil
+}
+
+func (b *kubernetesBackend) Status(globalFlags *types.GlobalFlags) error {
+	args := []string{"get", "pod", "-n", b.namespace, "-lapp=uyuni", "-o", "wide"}
+	if err := utils.RunInteractiveCmd("kubectl", withKubectlContext(args)); err != nil {
+		return err
+	}
+	fmt.Println()
+	return printReleaseHistory(os.Stdout, b.namespace, HELM_APP_NAME)
+}
+
+func (b *kubernetesBackend) GetContainerStatus() (*types.ContainerStatus, error) {
+	podName, err := GetPodName(b.namespace)
+	if err != nil {
+		return nil, err
+	}
+	client, err := getClientset()
+	if err != nil {
+		return nil, err
+	}
+	pod, err := client.CoreV1().Pods(b.namespace).Get(context.Background(), podName, metav1.GetOptions{})
+	if err != nil {
+		return nil, &utils.NotRunningError{Name: b.namespace, Backend: Name, Err: err}
+	}
+
+	status := types.ContainerStatus{Name: pod.Name, State: strings.ToLower(string(pod.Status.Phase))}
+	for _, container := range pod.Spec.Containers {
+		if container.Name == "uyuni" {
+			status.Image = container.Image
+		}
+	}
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		if containerStatus.Name != "uyuni" {
+			continue
+		}
+		switch {
+		case containerStatus.State.Running != nil:
+			status.Running = true
+			status.StartedAt = &containerStatus.State.Running.StartedAt.Time
+		case containerStatus.State.Waiting != nil:
+			status.State = containerStatus.State.Waiting.Reason
+		case containerStatus.State.Terminated != nil:
+			status.State = containerStatus.State.Terminated.Reason
+		}
+	}
+	return &status, nil
+}
+
+// checkNoDryRun fails with --dry-run as the changes done through the kubernetes API can't only be printed.
+func checkNoDryRun(globalFlags *types.GlobalFlags) error {
+	if globalFlags.DryRun {
+		return &utils.ConfigError{Message: "--dry-run is only supported by the uninstall command on kubernetes"}
+	}
+	return nil
+}
+
+func (b *kubernetesBackend) Install(viper *viper.Viper, globalFlags *types.GlobalFlags, fqdn string) (map[string]string, error) {
+	if err := checkNoDryRun(globalFlags); err != nil {
+		return nil, err
+	}
+	return installForKubernetes(viper, globalFlags, fqdn)
+}
+
+func (b *kubernetesBackend) CreateVolumes(viper *viper.Viper, globalFlags *types.GlobalFlags, force bool) error {
+	if err := checkNoDryRun(globalFlags); err != nil {
+		return err
+	}
+	return createVolumes(b.namespace, force, b.timeouts, globalFlags.Verbose)
+}
+
+func (b *kubernetesBackend) ImportVolume(viper *viper.Viper, globalFlags *types.GlobalFlags, name string, content io.Reader) error {
+	if err := checkNoDryRun(globalFlags); err != nil {
+		return err
+	}
+	image := fmt.Sprintf("%s:%s", viper.GetString("image"), viper.GetString("tag"))
+	return importVolume(b.namespace, image, name, content, b.timeouts, globalFlags.Verbose)
+}
+
+func (b *kubernetesBackend) Deploy(viper *viper.Viper, globalFlags *types.GlobalFlags, fqdn string) error {
+	if err := checkNoDryRun(globalFlags); err != nil {
+		return err
+	}
+	return deployForKubernetes(viper, globalFlags, fqdn, false)
+}
+
+func (b *kubernetesBackend) Migrate(viper *viper.Viper, globalFlags *types.GlobalFlags, sourceFqdn string) error {
+	if err := checkNoDryRun(globalFlags); err != nil {
+		return err
+	}
+	return migrateToKubernetes(viper, globalFlags, sourceFqdn)
+}
+
+func (b *kubernetesBackend) Upgrade(viper *viper.Viper, globalFlags *types.GlobalFlags) error {
+	if err := checkNoDryRun(globalFlags); err != nil {
+		return err
+	}
+	return upgradeKubernetes(viper, globalFlags)
+}
+
+func (b *kubernetesBackend) RenewCertificates(viper *viper.Viper, globalFlags *types.GlobalFlags, fqdn string, rotateCa bool) error {
+	if err := checkNoDryRun(globalFlags); err != nil {
+		return err
+	}
+	return renewCertificates(viper, globalFlags, fqdn, rotateCa)
+}
+
+func (b *kubernetesBackend) GetCertificates(viper *viper.Viper) ([]byte, []byte, error) {
+	return getCertificates(b.namespace)
+}
+
+func (b *kubernetesBackend) Uninstall(globalFlags *types.GlobalFlags, dryRun bool, purge bool) error {
+	return uninstallForKubernetes(globalFlags, dryRun)
+}
+
+// WaitReady waits for multi-user systemd target to be reached, at most for the server start timeout.
+func (b *kubernetesBackend) WaitReady() error {
+	return utils.WaitForServer(b, b.timeouts.ServerStart, true)
+}
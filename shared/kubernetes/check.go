@@ -0,0 +1,94 @@
+package kubernetes
+
+import (
+	"context"
+	"strings"
+
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// permissions lists the verbs and resources needed in the uyuni namespace.
+var permissions = [][]string{
+	{"create", "deployments"},
+	{"create", "persistentvolumeclaims"},
+	{"create", "jobs"},
+	{"create", "secrets"},
+	{"create", "configmaps"},
+	{"create", "pods/exec"},
+}
+
+func checkKubernetes(namespace string, fqdn string) []types.CheckResult {
+	results := []types.CheckResult{
+		utils.CheckBinary("kubectl", true),
+	}
+
+	if fqdn != "" {
+		results = append(results, utils.CheckFqdn(fqdn)...)
+	}
+
+	cluster := types.CheckResult{Name: "cluster connection", Status: types.CheckPass}
+	client, err := getClientset()
+	if err != nil {
+		cluster.Status = types.CheckFail
+		cluster.Message = err.Error()
+		return append(results, cluster)
+	}
+	version, err := client.Discovery().ServerVersion()
+	if err != nil {
+		cluster.Status = types.CheckFail
+		cluster.Message = err.Error()
+		return append(results, cluster)
+	}
+	cluster.Message = "connected to kubernetes " + version.GitVersion
+	results = append(results, cluster)
+
+	for _, permission := range permissions {
+		results = append(results, checkPermission(client, namespace, permission[0], permission[1], true))
+	}
+	// cert-manager installation needs to create its CRDs
+	results = append(results, checkPermission(client, "", "create", "customresourcedefinitions", false))
+
+	return results
+}
+
+// checkPermission verifies the user is allowed to run verb on resource.
+// An empty namespace means a cluster-wide permission.
+// The resource can have a subresource like pods/exec.
+func checkPermission(client kubernetes.Interface, namespace string, verb string, resource string, required bool) types.CheckResult {
+	name := "permission " + verb + " " + resource
+	if namespace != "" {
+		name += " in " + namespace
+	}
+
+	attributes := authorizationv1.ResourceAttributes{Namespace: namespace, Verb: verb, Resource: resource}
+	if parts := strings.SplitN(resource, "/", 2); len(parts) == 2 {
+		attributes.Resource = parts[0]
+		attributes.Subresource = parts[1]
+	}
+	switch attributes.Resource {
+	case "deployments":
+		attributes.Group = "apps"
+	case "jobs":
+		attributes.Group = "batch"
+	case "customresourcedefinitions":
+		attributes.Group = "apiextensions.k8s.io"
+	}
+	review := &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: &attributes},
+	}
+
+	result := types.CheckResult{Name: name, Status: types.CheckPass, Message: "allowed"}
+	response, err := client.AuthorizationV1().SelfSubjectAccessReviews().Create(context.Background(), review, metav1.CreateOptions{})
+	if err != nil || !response.Status.Allowed {
+		result.Status = types.CheckWarn
+		if required {
+			result.Status = types.CheckFail
+		}
+		result.Message = "denied"
+	}
+	return result
+}
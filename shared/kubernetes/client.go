@@ -0,0 +1,130 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+	watchtools "k8s.io/client-go/tools/watch"
+)
+
+var (
+	clientset     kubernetes.Interface
+	dynamicClient dynamic.Interface
+	kubeContext   string
+)
+
+// SetClientset replaces the client used to talk to the cluster, for instance by a fake clientset.
+func SetClientset(client kubernetes.Interface) {
+	clientset = client
+}
+
+// SetDynamicClient replaces the client used for the cert-manager and trust-manager resources,
+// for instance by a fake dynamic client.
+func SetDynamicClient(client dynamic.Interface) {
+	dynamicClient = client
+}
+
+// setKubeContext selects the kubeconfig context to use instead of the current one.
+// An empty context means the current context of the kubeconfig.
+func setKubeContext(context string) {
+	if context != kubeContext {
+		kubeContext = context
+		clientset = nil
+		dynamicClient = nil
+	}
+}
+
+// getRestConfig loads the kubeconfig from the KUBECONFIG environment variable or ~/.kube/config
+// for the selected context, without connecting to the cluster.
+func getRestConfig() (*rest.Config, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: kubeContext}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+}
+
+// getClientset returns the client to talk to the cluster of the selected kubeconfig context.
+func getClientset() (kubernetes.Interface, error) {
+	if clientset != nil {
+		return clientset, nil
+	}
+	config, err := getRestConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the kubeconfig: %w", err)
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the kubernetes client: %w", err)
+	}
+	clientset = client
+	return clientset, nil
+}
+
+// getDynamicClient returns the client for the custom resources of the cluster of the selected kubeconfig context.
+func getDynamicClient() (dynamic.Interface, error) {
+	if dynamicClient != nil {
+		return dynamicClient, nil
+	}
+	config, err := getRestConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the kubeconfig: %w", err)
+	}
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the kubernetes dynamic client: %w", err)
+	}
+	dynamicClient = client
+	return dynamicClient, nil
+}
+
+// kubectlCommand prepares a kubectl command for the selected kubeconfig context.
+func kubectlCommand(args ...string) *exec.Cmd {
+	return exec.Command("kubectl", withKubectlContext(args)...)
+}
+
+// withKubectlContext adds the selected kubeconfig context to kubectl arguments.
+func withKubectlContext(args []string) []string {
+	if kubeContext == "" {
+		return args
+	}
+	return append([]string{"--context", kubeContext}, args...)
+}
+
+// withHelmContext adds the selected kubeconfig context to helm arguments.
+func withHelmContext(args []string) []string {
+	if kubeContext == "" {
+		return args
+	}
+	return append([]string{"--kube-context", kubeContext}, args...)
+}
+
+// watchUntil lists and watches the objects of lw until condition returns true or an error.
+// The description tells what is waited for in the progress messages.
+// A zero timeout means no timeout.
+func watchUntil(description string, lw cache.ListerWatcher, objType runtime.Object, timeout time.Duration,
+	condition watchtools.ConditionFunc) error {
+	stop := utils.StartWaitProgress(description)
+	defer stop()
+
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	_, err := watchtools.UntilWithSync(ctx, lw, objType, nil, condition)
+	if errors.Is(err, wait.ErrWaitTimeout) {
+		return &utils.TimeoutError{Description: description, Timeout: timeout}
+	}
+	return err
+}
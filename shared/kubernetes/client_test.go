@@ -0,0 +1,44 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// setFakeClients replaces the clients by fake ones with the kubernetes objects and the custom resources.
+func setFakeClients(t *testing.T, objects []runtime.Object, resources ...runtime.Object) *fake.Clientset {
+	client := fake.NewSimpleClientset(objects...)
+	SetClientset(client)
+	SetDynamicClient(dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), resources...))
+	t.Cleanup(func() {
+		SetClientset(nil)
+		SetDynamicClient(nil)
+	})
+	return client
+}
+
+// newResource returns a custom resource with the given fields.
+func newResource(apiVersion string, kind string, namespace string, name string, fields map[string]interface{}) *unstructured.Unstructured {
+	object := map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"metadata":   map[string]interface{}{"name": name, "namespace": namespace},
+	}
+	for key, value := range fields {
+		object[key] = value
+	}
+	return &unstructured.Unstructured{Object: object}
+}
+
+// readyStatus returns the status of a cert-manager resource with a Ready condition.
+func readyStatus(status string) map[string]interface{} {
+	return map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []interface{}{map[string]interface{}{"type": "Ready", "status": status}},
+		},
+	}
+}
@@ -0,0 +1,274 @@
+package kubernetes
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"text/tabwriter"
+	"time"
+
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+// helmTimeout is how long helm waits for the resources when asked to.
+const helmTimeout = 300 * time.Second
+
+// getHelmSettings returns the helm settings for the selected kubeconfig context and namespace.
+// The other settings come from the HELM_* environment variables, like the helm command.
+func getHelmSettings(namespace string) *cli.EnvSettings {
+	settings := cli.New()
+	settings.KubeContext = kubeContext
+	settings.SetNamespace(namespace)
+	return settings
+}
+
+// getHelmConfig prepares the helm actions configuration for the releases in namespace.
+// The releases are stored in secrets like the helm command does by default.
+func getHelmConfig(settings *cli.EnvSettings, verbose bool) (*action.Configuration, error) {
+	debugLog := func(format string, v ...interface{}) {
+		if verbose {
+			log.Printf("helm: "+format+"\n", v...)
+		}
+	}
+
+	config := new(action.Configuration)
+	if err := config.Init(settings.RESTClientGetter(), settings.Namespace(), "secret", debugLog); err != nil {
+		return nil, fmt.Errorf("failed to initialize helm: %w", err)
+	}
+
+	// The registry client is needed for the oci:// charts
+	registryClient, err := registry.NewClient(
+		registry.ClientOptDebug(verbose),
+		registry.ClientOptCredentialsFile(settings.RegistryConfig),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the helm registry client: %w", err)
+	}
+	config.RegistryClient = registryClient
+	return config, nil
+}
+
+// loadChart downloads the chart if needed and loads it.
+// The chart can be a local archive or directory, an oci:// URL or a chart name in repo.
+func loadChart(pathOptions *action.ChartPathOptions, settings *cli.EnvSettings, repo string, name string, version string) (*chart.Chart, error) {
+	pathOptions.RepoURL = repo
+	pathOptions.Version = version
+	path, err := pathOptions.LocateChart(name, settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find helm chart %s: %w", name, err)
+	}
+	loaded, err := loader.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load helm chart %s: %w", name, err)
+	}
+	return loaded, nil
+}
+
+// installedByLabels returns the labels set on the charts installed by uyuniadm: only those are uninstalled.
+func installedByLabels() map[string]interface{} {
+	return map[string]interface{}{"installedby": "uyuniadm"}
+}
+
+// helmValues merges the values to pass to a chart.
+// The values computed by uyuniadm are overridden by the user's values file, if any,
+// and then by the values set from the command line flags.
+func helmValues(defaults map[string]interface{}, valuesFile string, overrides map[string]interface{}) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	mergeValues(values, defaults)
+	if valuesFile != "" {
+		fileValues, err := chartutil.ReadValuesFile(valuesFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read helm values file %s: %w", valuesFile, err)
+		}
+		mergeValues(values, fileValues)
+	}
+	mergeValues(values, overrides)
+	return values, nil
+}
+
+// mergeValues recursively copies the src values into dst, overriding the existing ones.
+func mergeValues(dst map[string]interface{}, src map[string]interface{}) {
+	for key, value := range src {
+		if srcMap, ok := value.(map[string]interface{}); ok {
+			if dstMap, ok := dst[key].(map[string]interface{}); ok {
+				mergeValues(dstMap, srcMap)
+				continue
+			}
+			copied := map[string]interface{}{}
+			mergeValues(copied, srcMap)
+			value = copied
+		}
+		dst[key] = value
+	}
+}
+
+// helmInstall installs a helm release or upgrades it if it already exists.
+// This allows to run the installation again after a partial failure.
+// If repo is not empty, the chart is looked for in that repository.
+// If version is empty, the latest chart version is used.
+func helmInstall(globalFlags *types.GlobalFlags, namespace string, repo string, name string, chart string, version string,
+	values map[string]interface{}) error {
+	settings := getHelmSettings(namespace)
+	config, err := getHelmConfig(settings, globalFlags.Verbose)
+	if err != nil {
+		return err
+	}
+
+	history, err := releaseHistory(config, name)
+	if err != nil && !errors.Is(err, driver.ErrReleaseNotFound) {
+		return err
+	}
+	if len(history) > 0 {
+		last := history[len(history)-1]
+		switch {
+		case last.Info.Status == release.StatusPendingInstall:
+			// An interrupted first installation can't be upgraded: start it again
+			log.Printf("Removing the %s helm release left by an interrupted installation\n", name)
+			if err := uninstallRelease(namespace, name, globalFlags.Verbose); err != nil {
+				return err
+			}
+		case last.Info.Status.IsPending():
+			return fmt.Errorf("helm release %s in namespace %s is %s, roll it back before installing again", name, namespace, last.Info.Status)
+		default:
+			log.Printf("Helm release %s already exists, upgrading it\n", name)
+			return upgradeRelease(config, settings, namespace, repo, name, chart, version, values, false)
+		}
+	}
+
+	install := action.NewInstall(config)
+	install.ReleaseName = name
+	install.Namespace = namespace
+	install.CreateNamespace = true
+	loaded, err := loadChart(&install.ChartPathOptions, settings, repo, chart, version)
+	if err != nil {
+		return err
+	}
+	installed, err := install.Run(loaded, values)
+	if err != nil {
+		return fmt.Errorf("failed to install helm chart %s in namespace %s: %w", chart, namespace, err)
+	}
+	log.Printf("Installed %s helm release revision %d\n", name, installed.Version)
+	return nil
+}
+
+// helmUpgrade upgrades an existing helm release, reusing its values.
+// If version is empty, the latest chart version is used.
+func helmUpgrade(globalFlags *types.GlobalFlags, namespace string, name string, chart string, version string,
+	values map[string]interface{}) error {
+	settings := getHelmSettings(namespace)
+	config, err := getHelmConfig(settings, globalFlags.Verbose)
+	if err != nil {
+		return err
+	}
+	return upgradeRelease(config, settings, namespace, "", name, chart, version, values, true)
+}
+
+func upgradeRelease(config *action.Configuration, settings *cli.EnvSettings, namespace string, repo string,
+	name string, chart string, version string, values map[string]interface{}, reuseValues bool) error {
+	upgrade := action.NewUpgrade(config)
+	upgrade.Namespace = namespace
+	upgrade.ReuseValues = reuseValues
+	loaded, err := loadChart(&upgrade.ChartPathOptions, settings, repo, chart, version)
+	if err != nil {
+		return err
+	}
+	upgraded, err := upgrade.Run(name, loaded, values)
+	if err != nil {
+		return fmt.Errorf("failed to upgrade helm chart %s in namespace %s: %w", chart, namespace, err)
+	}
+	log.Printf("Upgraded %s helm release to revision %d\n", name, upgraded.Version)
+	return nil
+}
+
+// uninstallRelease removes a helm release and the resources it created.
+func uninstallRelease(namespace string, name string, verbose bool) error {
+	config, err := getHelmConfig(getHelmSettings(namespace), verbose)
+	if err != nil {
+		return err
+	}
+	if _, err := action.NewUninstall(config).Run(name); err != nil {
+		return fmt.Errorf("failed to uninstall helm release %s: %w", name, err)
+	}
+	return nil
+}
+
+// rollbackRelease restores a previous revision of a helm release and waits for its resources to be ready.
+func rollbackRelease(namespace string, name string, revision int, verbose bool) error {
+	config, err := getHelmConfig(getHelmSettings(namespace), verbose)
+	if err != nil {
+		return err
+	}
+	rollback := action.NewRollback(config)
+	rollback.Version = revision
+	rollback.Wait = true
+	rollback.Timeout = helmTimeout
+	if err := rollback.Run(name); err != nil {
+		return fmt.Errorf("failed to roll back helm release %s to revision %d: %w", name, revision, err)
+	}
+	return nil
+}
+
+// getReleaseStatus returns the last revision of a helm release.
+func getReleaseStatus(namespace string, name string) (*release.Release, error) {
+	config, err := getHelmConfig(getHelmSettings(namespace), false)
+	if err != nil {
+		return nil, err
+	}
+	status, err := action.NewStatus(config).Run(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the %s helm release status: %w", name, err)
+	}
+	return status, nil
+}
+
+// getReleaseHistory returns the revisions of a helm release, the oldest first.
+func getReleaseHistory(namespace string, name string) ([]*release.Release, error) {
+	config, err := getHelmConfig(getHelmSettings(namespace), false)
+	if err != nil {
+		return nil, err
+	}
+	return releaseHistory(config, name)
+}
+
+// printReleaseHistory writes the status of a helm release and its revisions.
+func printReleaseHistory(out io.Writer, namespace string, name string) error {
+	history, err := getReleaseHistory(namespace, name)
+	if err != nil {
+		return err
+	}
+	last := history[len(history)-1]
+	fmt.Fprintf(out, "Helm release %s in namespace %s: revision %d %s\n", name, namespace, last.Version, last.Info.Status)
+
+	writer := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(writer, "REVISION\tUPDATED\tSTATUS\tCHART\tAPP VERSION\tDESCRIPTION")
+	for _, revision := range history {
+		chartName := ""
+		appVersion := ""
+		if revision.Chart != nil && revision.Chart.Metadata != nil {
+			chartName = revision.Chart.Metadata.Name + "-" + revision.Chart.Metadata.Version
+			appVersion = revision.Chart.Metadata.AppVersion
+		}
+		fmt.Fprintf(writer, "%d\t%s\t%s\t%s\t%s\t%s\n", revision.Version, revision.Info.LastDeployed.Format(time.RFC3339),
+			revision.Info.Status, chartName, appVersion, revision.Info.Description)
+	}
+	return writer.Flush()
+}
+
+func releaseHistory(config *action.Configuration, name string) ([]*release.Release, error) {
+	history, err := action.NewHistory(config).Run(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the %s helm release history: %w", name, err)
+	}
+	releaseutil.SortByRevision(history)
+	return history, nil
+}
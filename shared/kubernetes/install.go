@@ -0,0 +1,207 @@
+package kubernetes
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/viper"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+func installForKubernetes(viper *viper.Viper, globalFlags *types.GlobalFlags, fqdn string) (map[string]string, error) {
+	if err := deployForKubernetes(viper, globalFlags, fqdn, false); err != nil {
+		return nil, err
+	}
+
+	// Setup script env variables
+	return map[string]string{
+		"NO_SSL": "Y",
+	}, nil
+}
+
+// deployForKubernetes sets up the certificates, deploys the uyuni helm chart and waits for the server to start.
+// setUp tells whether the server data is already set up, like for a migrated server.
+func deployForKubernetes(viper *viper.Viper, globalFlags *types.GlobalFlags, fqdn string, setUp bool) error {
+	if err := checkIssuerParameters(viper); err != nil {
+		return err
+	}
+
+	var err error
+	if viper.GetBool("cert.useexisting") {
+		err = checkExistingCertificates(viper, fqdn)
+	} else if viper.GetString("cert.issuer.name") != "" {
+		// Use the user's issuer rather than the self-signed chain
+		err = checkExternalIssuer(viper)
+	} else {
+		// Install cert-manager and a self-signed issuer ready for use
+		err = installSslCertificates(viper, fqdn, globalFlags)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Expose the CA cert in uyuni-ca config map as the container shouldn't have the CA secret
+	if err := setupCaConfigMap(viper, globalFlags); err != nil {
+		return err
+	}
+
+	// Deploy the helm chart
+	if err := uyuniInstall(viper, fqdn, globalFlags); err != nil {
+		return err
+	}
+
+	// Wait for the pod to be started
+	if err := waitForDeployment(viper.GetString("helm.uyuni.namespace"), HELM_APP_NAME, "uyuni", globalFlags.Timeouts.WithDefaults()); err != nil {
+		return err
+	}
+	// The services of a server not set up yet fail until mgr-setup configures them or the database is restored
+	return utils.WaitForServer(NewBackend(globalFlags), globalFlags.Timeouts.WithDefaults().ServerStart, setUp)
+}
+
+// Install cert-manager and its CRDs using helm in the cert-manager namespace if needed
+// and then create a self-signed CA and issuers.
+func installSslCertificates(viper *viper.Viper, fqdn string, globalFlags *types.GlobalFlags) error {
+	// Install cert-manager if needed
+	if !isDeploymentReady("", "cert-manager") {
+		log.Println("Installing cert-manager")
+		repo := ""
+		chart := viper.GetString("helm.certmanager.chart")
+		version := viper.GetString("helm.certmanager.version")
+		namespace := viper.GetString("helm.certmanager.namespace")
+
+		values, err := helmValues(nil, viper.GetString("helm.certmanager.values"), map[string]interface{}{
+			"installCRDs": true,
+			"global":      map[string]interface{}{"commonLabels": installedByLabels()},
+		})
+		if err != nil {
+			return err
+		}
+
+		// Use upstream chart if nothing defined
+		if chart == "" {
+			repo = "https://charts.jetstack.io"
+			chart = "cert-manager"
+		}
+		// The installedby label will be used to only uninstall what we installed
+		if err := helmInstall(globalFlags, namespace, repo, "cert-manager", chart, version, values); err != nil {
+			return err
+		}
+	}
+
+	// Wait for cert-manager to be ready
+	if err := waitForDeployment("", "cert-manager-webhook", "webhook", globalFlags.Timeouts.WithDefaults()); err != nil {
+		return err
+	}
+
+	// Deploy self-signed issuer
+	const issuerTemplate = `apiVersion: cert-manager.io/v1
+kind: Issuer
+metadata:
+  name: uyuni-issuer
+  namespace: {{ .Namespace }}
+spec:
+  selfSigned: {}
+---
+apiVersion: cert-manager.io/v1
+kind: Certificate
+metadata:
+  name: uyuni-ca
+  namespace: {{ .Namespace }}
+spec:
+  isCA: true
+  subject:
+    countries: ["{{ .Country }}"]
+    provinces: ["{{ .State }}"]
+    localities: ["{{ .City }}"]
+    organizations: ["{{ .Org }}"]
+    organizationalUnits: ["{{ .OrgUnit }}"]
+  emailAddresses:
+    - {{ .Email }}
+  commonName: {{ .Fqdn }}
+  dnsNames:
+    - {{ .Fqdn }}
+  secretName: uyuni-ca
+  privateKey:
+    algorithm: {{ .KeyAlgorithm }}
+    {{- if ne .KeyAlgorithm "Ed25519" }}
+    size: {{ .KeySize }}
+    {{- end }}
+  issuerRef:
+    name: uyuni-issuer
+    kind: Issuer
+    group: cert-manager.io
+---
+apiVersion: cert-manager.io/v1
+kind: Issuer
+metadata:
+  name: uyuni-ca-issuer
+  namespace: {{ .Namespace }}
+spec:
+  ca:
+    secretName:
+      uyuni-ca
+`
+
+	log.Println("Creating issuer for self signed SSL certificate authority")
+	model := struct {
+		Country      string
+		State        string
+		City         string
+		Org          string
+		OrgUnit      string
+		Email        string
+		Fqdn         string
+		KeyAlgorithm string
+		KeySize      int
+		Namespace    string
+	}{
+		Country:      viper.GetString("cert.country"),
+		State:        viper.GetString("cert.state"),
+		City:         viper.GetString("cert.city"),
+		Org:          viper.GetString("cert.org"),
+		OrgUnit:      viper.GetString("cert.ou"),
+		Email:        viper.GetString("cert.email"),
+		Fqdn:         fqdn,
+		KeyAlgorithm: viper.GetString("cert.key.algorithm"),
+		KeySize:      viper.GetInt("cert.key.size"),
+		Namespace:    viper.GetString("helm.uyuni.namespace"),
+	}
+
+	if err := applyTemplate(issuerTemplate, model, "failed to create issuer", globalFlags.Verbose); err != nil {
+		return err
+	}
+
+	// Wait for issuer to be ready
+	timeout := globalFlags.Timeouts.WithDefaults().Certificates
+	return utils.WaitFor("the uyuni-ca-issuer to be ready", timeout, func() (bool, error) {
+		issuer, err := getResource(issuerResource, model.Namespace, selfSignedIssuerName)
+		return err == nil && isResourceReady(issuer), nil
+	})
+}
+
+func uyuniInstall(viper *viper.Viper, fqdn string, globalFlags *types.GlobalFlags) error {
+	log.Println("Installing Uyuni")
+
+	// The issuer annotations can be overwritten by the user's values.
+	// Existing certificates are already in the secret and need no issuer.
+	defaults := map[string]interface{}{}
+	if !viper.GetBool("cert.useexisting") {
+		defaults["ingressSslAnnotations"] = getIssuerAnnotations(viper)
+	}
+
+	// The values computed from the command line override what could be in the user's values
+	values, err := helmValues(defaults, viper.GetString("helm.uyuni.values"), map[string]interface{}{
+		"images":   map[string]interface{}{"server": fmt.Sprintf("%s:%s", viper.GetString("image"), viper.GetString("tag"))},
+		"timezone": viper.GetString("tz"),
+		"fqdn":     fqdn,
+	})
+	if err != nil {
+		return err
+	}
+
+	namespace := viper.GetString("helm.uyuni.namespace")
+	chart := viper.GetString("helm.uyuni.chart")
+	version := viper.GetString("helm.uyuni.version")
+	return helmInstall(globalFlags, namespace, "", HELM_APP_NAME, chart, version, values)
+}
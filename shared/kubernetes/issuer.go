@@ -0,0 +1,128 @@
+package kubernetes
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// selfSignedIssuerName is the issuer created by uyuniadm when no other issuer is configured.
+const selfSignedIssuerName = "uyuni-ca-issuer"
+
+var issuerKinds = []string{"Issuer", "ClusterIssuer"}
+
+var keyAlgorithms = []string{"RSA", "ECDSA", "Ed25519"}
+
+// defaultKeySizes are the private key sizes used when none is configured. Ed25519 keys have a fixed size.
+var defaultKeySizes = map[string]int{"RSA": 2048, "ECDSA": 256}
+
+// The private key sizes accepted by cert-manager.
+const (
+	minRsaKeySize = 2048
+	maxRsaKeySize = 8192
+)
+
+var ecdsaKeySizes = []string{"256", "384", "521"}
+
+// checkIssuerParameters validates the issuer and key flags and sets the default key size of the algorithm if needed.
+func checkIssuerParameters(viper *viper.Viper) error {
+	if kind := viper.GetString("cert.issuer.kind"); !utils.Contains(issuerKinds, kind) {
+		return &utils.ConfigError{
+			Message: fmt.Sprintf("invalid issuer kind %s, possible values are: %s", kind, strings.Join(issuerKinds, ", ")),
+		}
+	}
+	algorithm := viper.GetString("cert.key.algorithm")
+	if !utils.Contains(keyAlgorithms, algorithm) {
+		return &utils.ConfigError{
+			Message: fmt.Sprintf("invalid key algorithm %s, possible values are: %s", algorithm, strings.Join(keyAlgorithms, ", ")),
+		}
+	}
+	if algorithm == "Ed25519" {
+		return nil
+	}
+
+	size := viper.GetInt("cert.key.size")
+	switch {
+	case size == 0:
+		viper.Set("cert.key.size", defaultKeySizes[algorithm])
+	case algorithm == "RSA" && (size < minRsaKeySize || size > maxRsaKeySize):
+		return &utils.ConfigError{
+			Message: fmt.Sprintf("invalid RSA key size %d, it needs to be between %d and %d", size, minRsaKeySize, maxRsaKeySize),
+		}
+	case algorithm == "ECDSA" && !utils.Contains(ecdsaKeySizes, strconv.Itoa(size)):
+		return &utils.ConfigError{
+			Message: fmt.Sprintf("invalid ECDSA key size %d, possible values are: %s", size, strings.Join(ecdsaKeySizes, ", ")),
+		}
+	}
+	return nil
+}
+
+// getIssuerAnnotations returns the ingress annotations for cert-manager to issue the server certificate.
+func getIssuerAnnotations(viper *viper.Viper) map[string]interface{} {
+	name := viper.GetString("cert.issuer.name")
+	kind := viper.GetString("cert.issuer.kind")
+	if name == "" {
+		name = selfSignedIssuerName
+		kind = "Issuer"
+	}
+
+	issuerAnnotation := "cert-manager.io/issuer"
+	if kind == "ClusterIssuer" {
+		issuerAnnotation = "cert-manager.io/cluster-issuer"
+	}
+	annotations := map[string]interface{}{
+		issuerAnnotation:                        name,
+		"cert-manager.io/private-key-algorithm": viper.GetString("cert.key.algorithm"),
+	}
+	if viper.GetString("cert.key.algorithm") != "Ed25519" {
+		annotations["cert-manager.io/private-key-size"] = strconv.Itoa(viper.GetInt("cert.key.size"))
+	}
+	return annotations
+}
+
+// checkExternalIssuer verifies the issuer configured by the user is ready and stores the CA certificate
+// in the uyuni-ca secret: the server certificate is only issued once the helm chart is installed,
+// but the CA certificate is needed earlier.
+func checkExternalIssuer(viper *viper.Viper) error {
+	name := viper.GetString("cert.issuer.name")
+	kind := viper.GetString("cert.issuer.kind")
+	namespace := viper.GetString("helm.uyuni.namespace")
+
+	resource, issuerNamespace := clusterIssuerResource, ""
+	if kind == "Issuer" {
+		resource, issuerNamespace = issuerResource, namespace
+	}
+	issuer, err := getResource(resource, issuerNamespace, name)
+	if err != nil {
+		return fmt.Errorf("failed to get %s %s: %w", kind, name, err)
+	}
+	if !isResourceReady(issuer) {
+		return fmt.Errorf("%s %s is not ready", kind, name)
+	}
+
+	caPath := viper.GetString("cert.ca")
+	if caPath == "" {
+		data, err := getSecretData(namespace, caSecretName)
+		if err != nil {
+			return err
+		}
+		if data == nil {
+			return &utils.ConfigError{Message: fmt.Sprintf("--cert-ca is required to provide the CA certificate of %s %s", kind, name)}
+		}
+		return nil
+	}
+
+	if _, err := utils.ReadCertificates(caPath); err != nil {
+		return err
+	}
+	ca, err := readFile(caPath)
+	if err != nil {
+		return err
+	}
+	log.Printf("Importing the CA certificate of %s %s\n", kind, name)
+	return applySecret(namespace, caSecretName, "Opaque", map[string][]byte{"ca.crt": ca})
+}
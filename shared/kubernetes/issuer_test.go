@@ -0,0 +1,118 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestCheckExternalIssuer(t *testing.T) {
+	caSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: caSecretName, Namespace: "uyuni"},
+		Data:       map[string][]byte{"ca.crt": []byte("CA")},
+	}
+	tests := []struct {
+		name        string
+		kind        string
+		objects     []runtime.Object
+		resources   []runtime.Object
+		expectedErr bool
+	}{
+		{
+			name:      "ready issuer",
+			kind:      "Issuer",
+			objects:   []runtime.Object{caSecret},
+			resources: []runtime.Object{newResource("cert-manager.io/v1", "Issuer", "uyuni", "external", readyStatus("True"))},
+		},
+		{
+			name:      "ready cluster issuer",
+			kind:      "ClusterIssuer",
+			objects:   []runtime.Object{caSecret},
+			resources: []runtime.Object{newResource("cert-manager.io/v1", "ClusterIssuer", "", "external", readyStatus("True"))},
+		},
+		{
+			name:        "issuer not ready",
+			kind:        "Issuer",
+			objects:     []runtime.Object{caSecret},
+			resources:   []runtime.Object{newResource("cert-manager.io/v1", "Issuer", "uyuni", "external", readyStatus("False"))},
+			expectedErr: true,
+		},
+		{
+			name:        "issuer in another namespace",
+			kind:        "Issuer",
+			objects:     []runtime.Object{caSecret},
+			resources:   []runtime.Object{newResource("cert-manager.io/v1", "Issuer", "other", "external", readyStatus("True"))},
+			expectedErr: true,
+		},
+		{
+			name:        "missing CA",
+			kind:        "Issuer",
+			resources:   []runtime.Object{newResource("cert-manager.io/v1", "Issuer", "uyuni", "external", readyStatus("True"))},
+			expectedErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			setFakeClients(t, test.objects, test.resources...)
+
+			viper := viper.New()
+			viper.Set("cert.issuer.name", "external")
+			viper.Set("cert.issuer.kind", test.kind)
+			viper.Set("helm.uyuni.namespace", "uyuni")
+
+			err := checkExternalIssuer(viper)
+			if test.expectedErr && err == nil {
+				t.Error("expected an error")
+			} else if !test.expectedErr && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+func TestCheckIssuerParameters(t *testing.T) {
+	tests := []struct {
+		name         string
+		algorithm    string
+		size         int
+		expectedSize int
+		expectedErr  bool
+	}{
+		{name: "default RSA size", algorithm: "RSA", expectedSize: 2048},
+		{name: "default ECDSA size", algorithm: "ECDSA", expectedSize: 256},
+		{name: "RSA size", algorithm: "RSA", size: 4096, expectedSize: 4096},
+		{name: "ECDSA size", algorithm: "ECDSA", size: 521, expectedSize: 521},
+		{name: "ignored Ed25519 size", algorithm: "Ed25519", size: 1000, expectedSize: 1000},
+		{name: "too small RSA size", algorithm: "RSA", size: 1000, expectedErr: true},
+		{name: "too big RSA size", algorithm: "RSA", size: 16384, expectedErr: true},
+		{name: "RSA size for ECDSA", algorithm: "ECDSA", size: 2048, expectedErr: true},
+		{name: "invalid algorithm", algorithm: "DSA", expectedErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			viper := viper.New()
+			viper.Set("cert.issuer.kind", "Issuer")
+			viper.Set("cert.key.algorithm", test.algorithm)
+			viper.Set("cert.key.size", test.size)
+
+			err := checkIssuerParameters(viper)
+			if test.expectedErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if size := viper.GetInt("cert.key.size"); size != test.expectedSize {
+				t.Errorf("expected key size %d, got %d", test.expectedSize, size)
+			}
+		})
+	}
+}
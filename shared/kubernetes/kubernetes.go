@@ -0,0 +1,264 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"text/template"
+	"time"
+
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+const HELM_APP_NAME = "uyuni"
+
+// waitForDeployment waits for a kubernetes deployment to have at least one ready replica.
+// Finding the pod and getting it ready are limited by the deployment timeout,
+// pulling its images by the image pull timeout.
+// An empty namespace means searching through all the namespaces.
+func waitForDeployment(namespace string, name string, appName string, timeouts types.Timeouts) error {
+	client, err := getClientset()
+	if err != nil {
+		return err
+	}
+
+	// Find a replica pod
+	// Using the app label is a shortcut, not the 100% acurate way to get from deployment to pod
+	var pod *corev1.Pod
+	podsWatcher := newPodsWatcher(client, namespace, "app="+appName, "")
+	err = watchUntil("a pod of deployment "+name, podsWatcher, &corev1.Pod{}, timeouts.Deployment,
+		func(event watch.Event) (bool, error) {
+			if found, ok := event.Object.(*corev1.Pod); ok && event.Type != watch.Deleted {
+				pod = found
+				return true, nil
+			}
+			return false, nil
+		})
+	if err != nil {
+		return err
+	}
+
+	// We need to wait for the image to be pulled as this can add quite some time
+	if err := waitForPulledImage(pod.Namespace, pod.Name, timeouts.ImagePull); err != nil {
+		return err
+	}
+
+	// Wait for a replica to be ready
+	podsWatcher = newPodsWatcher(client, namespace, "app="+appName, "")
+	return watchUntil(fmt.Sprintf("deployment %s to be ready", name), podsWatcher, &corev1.Pod{}, timeouts.Deployment,
+		func(event watch.Event) (bool, error) {
+			found, ok := event.Object.(*corev1.Pod)
+			if !ok || event.Type == watch.Deleted {
+				return false, nil
+			}
+			if isPodReady(found) {
+				return true, nil
+			}
+			return false, getPodFailure(found)
+		})
+}
+
+// waitForPulledImage watches a pod until the images of its containers are pulled.
+// The image pull failures are terminal: kubernetes would only retry them.
+func waitForPulledImage(namespace string, podName string, timeout time.Duration) error {
+	client, err := getClientset()
+	if err != nil {
+		return err
+	}
+
+	podsWatcher := newPodsWatcher(client, namespace, "", podName)
+	return watchUntil("the images of pod "+podName+" to be pulled", podsWatcher, &corev1.Pod{}, timeout,
+		func(event watch.Event) (bool, error) {
+			pod, ok := event.Object.(*corev1.Pod)
+			if !ok || pod.Name != podName {
+				return false, nil
+			}
+			if event.Type == watch.Deleted {
+				return false, fmt.Errorf("pod %s was deleted", podName)
+			}
+			if err := getPodFailure(pod); err != nil {
+				return false, err
+			}
+			return arePodImagesPulled(pod), nil
+		})
+}
+
+// arePodImagesPulled returns whether the images of all the containers of a pod are pulled.
+func arePodImagesPulled(pod *corev1.Pod) bool {
+	if len(pod.Status.ContainerStatuses) < len(pod.Spec.Containers) {
+		return false
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		// The image ID is only known once the image is pulled
+		if status.ImageID == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// podFailureReasons are the waiting reasons of containers that won't recover by themselves.
+var podFailureReasons = []string{
+	"CrashLoopBackOff",
+	"ImagePullBackOff",
+	"ErrImagePull",
+	"ErrImageNeverPull",
+	"InvalidImageName",
+	"CreateContainerConfigError",
+}
+
+// getPodFailure returns an error if the pod or one of its containers failed in a way waiting can't fix.
+func getPodFailure(pod *corev1.Pod) error {
+	if pod.Status.Phase == corev1.PodFailed {
+		return fmt.Errorf("pod %s failed: %s", pod.Name, pod.Status.Message)
+	}
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		if waiting := status.State.Waiting; waiting != nil && utils.Contains(podFailureReasons, waiting.Reason) {
+			return fmt.Errorf("container %s of pod %s: %s: %s", status.Name, pod.Name, waiting.Reason, waiting.Message)
+		}
+	}
+	return nil
+}
+
+// isPodReady returns whether the Ready condition of a pod is true.
+func isPodReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// newPodsWatcher lists and watches the pods matching a label selector or with a name.
+func newPodsWatcher(client kubernetes.Interface, namespace string, labelSelector string, name string) cache.ListerWatcher {
+	setOptions := func(options *metav1.ListOptions) {
+		options.LabelSelector = labelSelector
+		if name != "" {
+			options.FieldSelector = "metadata.name=" + name
+		}
+	}
+	return &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			setOptions(&options)
+			return client.CoreV1().Pods(namespace).List(context.Background(), options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			setOptions(&options)
+			return client.CoreV1().Pods(namespace).Watch(context.Background(), options)
+		},
+	}
+}
+
+// waitForRollout waits for all the pods of a deployment to be updated and available, like kubectl rollout status.
+func waitForRollout(namespace string, name string, timeout time.Duration) error {
+	client, err := getClientset()
+	if err != nil {
+		return err
+	}
+	deployments := client.AppsV1().Deployments(namespace)
+	deploymentsWatcher := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = "metadata.name=" + name
+			return deployments.List(context.Background(), options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = "metadata.name=" + name
+			return deployments.Watch(context.Background(), options)
+		},
+	}
+	return watchUntil(fmt.Sprintf("deployment %s to roll out", name), deploymentsWatcher, &appsv1.Deployment{}, timeout,
+		func(event watch.Event) (bool, error) {
+			deployment, ok := event.Object.(*appsv1.Deployment)
+			if !ok || deployment.Name != name {
+				return false, nil
+			}
+			return isRolledOut(deployment)
+		})
+}
+
+// isRolledOut returns whether the latest revision of a deployment replaced all its pods.
+// A deployment which exceeded its progress deadline is reported as an error.
+func isRolledOut(deployment *appsv1.Deployment) (bool, error) {
+	// The status doesn't describe the latest revision yet
+	if deployment.Generation > deployment.Status.ObservedGeneration {
+		return false, nil
+	}
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentProgressing && condition.Reason == "ProgressDeadlineExceeded" {
+			return false, fmt.Errorf("deployment %s exceeded its progress deadline", deployment.Name)
+		}
+	}
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	status := deployment.Status
+	return status.UpdatedReplicas >= replicas && status.Replicas <= status.UpdatedReplicas &&
+		status.AvailableReplicas >= status.UpdatedReplicas, nil
+}
+
+// isDeploymentReady returns true if a kubernetes deployment has at least one ready replica.
+// An empty namespace means searching through all the namespaces.
+func isDeploymentReady(namespace string, name string) bool {
+	for _, deployment := range findDeployments(namespace, name, "") {
+		if deployment.Status.ReadyReplicas > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// findDeployments returns the deployments with a name, optionally filtered by a label selector.
+// An empty namespace means searching through all the namespaces.
+// Failing to list the deployments is reported as no deployment found.
+func findDeployments(namespace string, name string, labelSelector string) []appsv1.Deployment {
+	client, err := getClientset()
+	if err != nil {
+		return nil
+	}
+	list, err := client.AppsV1().Deployments(namespace).List(context.Background(), metav1.ListOptions{
+		FieldSelector: "metadata.name=" + name,
+		LabelSelector: labelSelector,
+	})
+	if err != nil {
+		return nil
+	}
+	deployments := []appsv1.Deployment{}
+	for _, deployment := range list.Items {
+		// Field selectors are not implemented by all clients
+		if deployment.Name == name {
+			deployments = append(deployments, deployment)
+		}
+	}
+	return deployments
+}
+
+// applyTemplate renders a kubernetes resources definition template and runs kubectl apply on it.
+func applyTemplate(definition string, model interface{}, errMessage string, verbose bool) error {
+	var buf bytes.Buffer
+	t := template.Must(template.New("resources").Parse(definition))
+	if err := t.Execute(&buf, model); err != nil {
+		return fmt.Errorf("failed to generate the kubernetes resources definition: %w", err)
+	}
+
+	if verbose {
+		log.Printf("Applying kubernetes resources:\n%s\n", buf.String())
+	}
+	cmd := kubectlCommand("apply", "-f", "-")
+	cmd.Stdin = &buf
+	if out, err := utils.GetRunner().CombinedOutput(cmd); err != nil {
+		return utils.NewCmdError(errMessage, cmd, out, err)
+	}
+	return nil
+}
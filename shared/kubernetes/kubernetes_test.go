@@ -0,0 +1,101 @@
+package kubernetes
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func newDeployment(namespace string, name string, generation int64, status appsv1.DeploymentStatus) *appsv1.Deployment {
+	replicas := int32(1)
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Generation: generation},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		Status:     status,
+	}
+}
+
+func TestWaitForRollout(t *testing.T) {
+	tests := []struct {
+		name        string
+		deployment  *appsv1.Deployment
+		expectedErr error
+	}{
+		{
+			name: "rolled out",
+			deployment: newDeployment("uyuni", HELM_APP_NAME, 2, appsv1.DeploymentStatus{
+				ObservedGeneration: 2, Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1,
+			}),
+		},
+		{
+			name: "old pod still running",
+			deployment: newDeployment("uyuni", HELM_APP_NAME, 2, appsv1.DeploymentStatus{
+				ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 1, AvailableReplicas: 1,
+			}),
+			expectedErr: &utils.TimeoutError{},
+		},
+		{
+			name: "new revision not observed",
+			deployment: newDeployment("uyuni", HELM_APP_NAME, 3, appsv1.DeploymentStatus{
+				ObservedGeneration: 2, Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1,
+			}),
+			expectedErr: &utils.TimeoutError{},
+		},
+		{
+			name: "progress deadline exceeded",
+			deployment: newDeployment("uyuni", HELM_APP_NAME, 2, appsv1.DeploymentStatus{
+				ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 1,
+				Conditions: []appsv1.DeploymentCondition{
+					{Type: appsv1.DeploymentProgressing, Reason: "ProgressDeadlineExceeded"},
+				},
+			}),
+			expectedErr: errors.New("deployment uyuni exceeded its progress deadline"),
+		},
+		{
+			name:        "deployment in another namespace",
+			deployment:  newDeployment("other", HELM_APP_NAME, 1, appsv1.DeploymentStatus{ObservedGeneration: 1}),
+			expectedErr: &utils.TimeoutError{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			setFakeClients(t, []runtime.Object{test.deployment})
+
+			err := waitForRollout("uyuni", HELM_APP_NAME, 200*time.Millisecond)
+			switch expected := test.expectedErr.(type) {
+			case nil:
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			case *utils.TimeoutError:
+				var timeoutErr *utils.TimeoutError
+				if !errors.As(err, &timeoutErr) {
+					t.Errorf("expected a timeout, got %v", err)
+				}
+			default:
+				if err == nil || err.Error() != expected.Error() {
+					t.Errorf("expected %q, got %v", expected, err)
+				}
+			}
+		})
+	}
+}
+
+func TestGetServerNamespaces(t *testing.T) {
+	setFakeClients(t, []runtime.Object{
+		newDeployment("uyuni", HELM_APP_NAME, 1, appsv1.DeploymentStatus{}),
+		newDeployment("uyuni-test", HELM_APP_NAME, 1, appsv1.DeploymentStatus{}),
+		newDeployment("cert-manager", "cert-manager", 1, appsv1.DeploymentStatus{}),
+	})
+
+	namespaces := getServerNamespaces("uyuni")
+	if len(namespaces) != 1 || namespaces[0] != "uyuni-test" {
+		t.Errorf("expected [uyuni-test], got %v", namespaces)
+	}
+}
@@ -0,0 +1,409 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+const migrationJobName = "uyuni-migration"
+
+// migrationFilesName is the config map holding the migration script and the ssh configuration.
+const migrationFilesName = "uyuni-migration-files"
+
+// migrationStateName is the persistent volume claim storing the migration progress between the runs of the job.
+const migrationStateName = "uyuni-migration-state"
+
+func migrateToKubernetes(viper *viper.Viper, globalFlags *types.GlobalFlags, sourceFqdn string) error {
+	final := viper.GetBool("final")
+	namespace := viper.GetString("helm.uyuni.namespace")
+	timeouts := globalFlags.Timeouts.WithDefaults()
+	script, err := utils.RenderMigrationScript(sourceFqdn, true, final)
+	if err != nil {
+		return err
+	}
+
+	// The volumes need to be ready before the job can use them
+	if err := ensureVolumes(namespace, globalFlags.Verbose); err != nil {
+		return err
+	}
+	if err := ensureMigrationState(namespace, globalFlags.Verbose); err != nil {
+		return err
+	}
+
+	if final {
+		log.Println("Migrating server")
+	} else {
+		log.Println("Pre-synchronizing server data")
+	}
+	if err := runMigrationJob(namespace, script, viper.GetString("image"), viper.GetString("tag"), timeouts,
+		globalFlags.Verbose); err != nil {
+		return err
+	}
+
+	if !final {
+		log.Println("Data pre-synchronized: run again to catch up with the source changes or with --final to finish the migration")
+		return nil
+	}
+
+	// Deploy the server with the values of the migrated one
+	output, err := getMigrationJobOutput(namespace)
+	if err != nil {
+		return err
+	}
+	timezone, err := utils.ParseMigrationTimezone(output)
+	if err != nil {
+		return err
+	}
+	viper.Set("tz", timezone)
+	if err := deployForKubernetes(viper, globalFlags, sourceFqdn, true); err != nil {
+		return err
+	}
+
+	if err := deleteMigrationState(namespace, globalFlags.Verbose); err != nil {
+		log.Println(err)
+	}
+	log.Println("Server migrated")
+	return nil
+}
+
+type volume struct {
+	// HostPath is the path of the mounted host file, the persistent volume claim named after the volume is mounted if empty.
+	HostPath string
+	Path     string
+}
+
+// migrationFile is a file of the migration files config map and where it is mounted in the job.
+type migrationFile struct {
+	Key  string
+	Path string
+}
+
+// ensureMigrationState creates the persistent volume claim storing the migration progress if needed.
+// The progress is kept in the cluster as the job can run on another node than the tools.
+func ensureMigrationState(namespace string, verbose bool) error {
+	model := struct {
+		Namespace string
+		Volumes   map[string]string
+	}{
+		Namespace: namespace,
+		Volumes:   map[string]string{migrationStateName: "10Mi"},
+	}
+	return applyTemplate(pvcTemplate, model, "failed to create the migration state persistent volume claim", verbose)
+}
+
+// deleteMigrationState removes the persistent volume claim storing the migration progress.
+func deleteMigrationState(namespace string, verbose bool) error {
+	return deleteVolumes(namespace, []string{migrationStateName}, verbose)
+}
+
+// createMigrationFiles replaces the config map with the migration script and the local ssh configuration files.
+// It returns the files to mount in the job.
+func createMigrationFiles(namespace string, script string) ([]migrationFile, error) {
+	client, err := getClientset()
+	if err != nil {
+		return nil, err
+	}
+
+	sshConfigPath, sshKnownhostsPath, err := utils.GetSshPaths()
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]string{"migrate.sh": script}
+	files := []migrationFile{{Key: "migrate.sh", Path: "/var/lib/uyuni-tools/migrate.sh"}}
+	sshFiles := []struct {
+		key       string
+		localPath string
+		path      string
+	}{
+		{"ssh-config", sshConfigPath, "/root/.ssh/config"},
+		{"ssh-known-hosts", sshKnownhostsPath, "/root/.ssh/known_hosts"},
+	}
+	for _, file := range sshFiles {
+		content, err := os.ReadFile(file.localPath)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file.localPath, err)
+		}
+		data[file.key] = string(content)
+		files = append(files, migrationFile{Key: file.key, Path: file.path})
+	}
+
+	configMaps := client.CoreV1().ConfigMaps(namespace)
+	if err := configMaps.Delete(context.Background(), migrationFilesName, metav1.DeleteOptions{}); err != nil &&
+		!apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to remove the previous migration files config map: %w", err)
+	}
+	configMap := corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: migrationFilesName, Namespace: namespace},
+		Data:       data,
+	}
+	if _, err := configMaps.Create(context.Background(), &configMap, metav1.CreateOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to create the migration files config map: %w", err)
+	}
+	return files, nil
+}
+
+// runMigrationJob starts the migration job, streams its logs and waits for it to succeed.
+// A previous migration job is removed first.
+func runMigrationJob(namespace string, script string, image string, tag string, timeouts types.Timeouts, verbose bool) error {
+	sshAuthSocket, err := utils.GetSshAuthSocket()
+	if err != nil {
+		return err
+	}
+
+	// The ssh agent can't be passed to the cluster: the job needs to run on the local node.
+	node, err := getLocalNode()
+	if err != nil {
+		return err
+	}
+
+	files, err := createMigrationFiles(namespace, script)
+	if err != nil {
+		return err
+	}
+
+	volumes := make(map[string]volume)
+	for name, path := range utils.VOLUMES {
+		volumes[name] = volume{Path: path}
+	}
+	volumes[migrationStateName] = volume{Path: utils.MigrationStatePath}
+	volumes["ssh-auth-socket"] = volume{HostPath: sshAuthSocket, Path: "/tmp/ssh_auth_sock"}
+
+	model := struct {
+		Name      string
+		Namespace string
+		Volumes   map[string]volume
+		FilesName string
+		Files     []migrationFile
+		Image     string
+		Tag       string
+		Node      string
+	}{
+		Name:      migrationJobName,
+		Namespace: namespace,
+		Volumes:   volumes,
+		FilesName: migrationFilesName,
+		Files:     files,
+		Image:     image,
+		Tag:       tag,
+		Node:      node,
+	}
+
+	if err := deleteMigrationJob(namespace, timeouts.Deployment); err != nil {
+		return err
+	}
+	if err := applyTemplate(migrationJob, model, "failed to start migration job", verbose); err != nil {
+		return err
+	}
+
+	// The logs are only streamed until the job times out
+	deadline := time.Now().Add(timeouts.ImagePull + timeouts.Migration)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	// Stream the logs, waiting for the image to be pulled
+	logsCmd := exec.CommandContext(ctx, "kubectl", withKubectlContext([]string{"logs", "-f", "-n", namespace,
+		"--pod-running-timeout=" + timeouts.ImagePull.String(), "job/" + migrationJobName})...)
+	logsCmd.Stdout = os.Stdout
+	logsCmd.Stderr = os.Stderr
+	if err := utils.GetRunner().Run(logsCmd); err != nil {
+		log.Printf("Failed to get the migration job logs: %s\n", err)
+	}
+	return waitForMigrationJob(namespace, time.Until(deadline))
+}
+
+// getHostname returns the name of the machine running the tools, replaced in the tests.
+var getHostname = os.Hostname
+
+// getLocalNode returns the kubernetes.io/hostname label of the cluster node running the tools.
+// An error is returned if there are several nodes and none matches the local host name.
+func getLocalNode() (string, error) {
+	client, err := getClientset()
+	if err != nil {
+		return "", err
+	}
+	nodes, err := client.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to list the cluster nodes: %w", err)
+	}
+	if len(nodes.Items) == 1 {
+		return getNodeHostname(&nodes.Items[0]), nil
+	}
+
+	hostname, err := getHostname()
+	if err != nil {
+		return "", fmt.Errorf("failed to get the local host name: %w", err)
+	}
+	shortName := strings.SplitN(hostname, ".", 2)[0]
+	for i := range nodes.Items {
+		nodeHostname := getNodeHostname(&nodes.Items[i])
+		for _, name := range []string{nodeHostname, nodes.Items[i].Name} {
+			if strings.EqualFold(name, hostname) || strings.EqualFold(strings.SplitN(name, ".", 2)[0], shortName) {
+				return nodeHostname, nil
+			}
+		}
+	}
+	return "", &utils.ConfigError{Message: fmt.Sprintf("no cluster node matches the %s local host: "+
+		"the migration job uses the local ssh agent and needs to be run from a cluster node", hostname)}
+}
+
+// getNodeHostname returns the kubernetes.io/hostname label of a node, defaulting to its name.
+func getNodeHostname(node *corev1.Node) string {
+	if hostname, ok := node.Labels["kubernetes.io/hostname"]; ok {
+		return hostname
+	}
+	return node.Name
+}
+
+// deleteMigrationJob removes the previous migration job and waits for it and its pods to be gone:
+// the output of the new job is read from its pod.
+func deleteMigrationJob(namespace string, timeout time.Duration) error {
+	client, err := getClientset()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	jobs := client.BatchV1().Jobs(namespace)
+	propagation := metav1.DeletePropagationBackground
+	err = jobs.Delete(ctx, migrationJobName, metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to remove previous migration job: %w", err)
+	}
+
+	return utils.WaitFor("the previous migration job to be removed", timeout, func() (bool, error) {
+		if _, err := jobs.Get(ctx, migrationJobName, metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		pods, err := client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: "job-name=" + migrationJobName})
+		return err == nil && len(pods.Items) == 0, nil
+	})
+}
+
+// waitForMigrationJob waits for the migration job to end and fails if it didn't succeed.
+func waitForMigrationJob(namespace string, timeout time.Duration) error {
+	client, err := getClientset()
+	if err != nil {
+		return err
+	}
+	// A zero timeout would mean waiting forever
+	if timeout <= 0 {
+		timeout = time.Second
+	}
+	jobs := client.BatchV1().Jobs(namespace)
+	jobsWatcher := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = "metadata.name=" + migrationJobName
+			return jobs.List(context.Background(), options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = "metadata.name=" + migrationJobName
+			return jobs.Watch(context.Background(), options)
+		},
+	}
+	return watchUntil("the migration job to finish", jobsWatcher, &batchv1.Job{}, timeout, func(event watch.Event) (bool, error) {
+		job, ok := event.Object.(*batchv1.Job)
+		if !ok || job.Name != migrationJobName {
+			return false, nil
+		}
+		if job.Status.Succeeded > 0 {
+			return true, nil
+		}
+		if job.Status.Failed > 0 {
+			return false, fmt.Errorf("migration job failed, run kubectl logs -n %s job/%s for details", namespace, migrationJobName)
+		}
+		return false, nil
+	})
+}
+
+// getMigrationJobOutput returns the logs of the succeeded migration job pod.
+func getMigrationJobOutput(namespace string) (string, error) {
+	client, err := getClientset()
+	if err != nil {
+		return "", err
+	}
+	pods, err := client.CoreV1().Pods(namespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: "job-name=" + migrationJobName,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to find the migration job pod: %w", err)
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodSucceeded {
+			continue
+		}
+		out, err := client.CoreV1().Pods(namespace).GetLogs(pod.Name, &corev1.PodLogOptions{}).DoRaw(context.Background())
+		if err != nil {
+			return "", fmt.Errorf("failed to read the migration job logs: %w", err)
+		}
+		return string(out), nil
+	}
+	return "", fmt.Errorf("no succeeded pod for the %s job", migrationJobName)
+}
+
+const migrationJob = `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: {{ .Name }}
+  namespace: {{ .Namespace }}
+spec:
+  backoffLimit: 0
+  template:
+    spec:
+      restartPolicy: Never
+      nodeSelector:
+        kubernetes.io/hostname: {{ .Node }}
+      containers:
+      - name: migration
+        image: {{ .Image }}:{{ .Tag }}
+        command: [ "/var/lib/uyuni-tools/migrate.sh" ]
+        env:
+          - name: SSH_AUTH_SOCK
+            value: /tmp/ssh_auth_sock
+        volumeMounts:
+        {{- range $name, $volume := .Volumes }}
+          - mountPath: {{ $volume.Path }}
+            name: {{ $name }}
+        {{- end }}
+        {{- range .Files }}
+          - mountPath: {{ .Path }}
+            name: migration-files
+            subPath: {{ .Key }}
+        {{- end }}
+      volumes:
+      {{- range $name, $volume := .Volumes }}
+        - name: {{ $name }}
+        {{- if eq $volume.HostPath "" }}
+          persistentVolumeClaim:
+            claimName: {{ $name }}
+        {{- else }}
+          hostPath:
+            path: {{ $volume.HostPath }}
+        {{- end }}
+      {{- end }}
+        - name: migration-files
+          configMap:
+            name: {{ .FilesName }}
+            defaultMode: 0555
+`
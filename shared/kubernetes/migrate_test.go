@@ -0,0 +1,136 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestWaitForMigrationJob(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      batchv1.JobStatus
+		expectedErr bool
+	}{
+		{"succeeded", batchv1.JobStatus{Succeeded: 1}, false},
+		{"failed", batchv1.JobStatus{Failed: 1}, true},
+		{"still running", batchv1.JobStatus{Active: 1}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			setFakeClients(t, []runtime.Object{&batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{Name: migrationJobName, Namespace: "uyuni"},
+				Status:     test.status,
+			}})
+
+			err := waitForMigrationJob("uyuni", 200*time.Millisecond)
+			if test.expectedErr && err == nil {
+				t.Error("expected an error")
+			} else if !test.expectedErr && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+func TestGetMigrationJobOutput(t *testing.T) {
+	pod := func(name string, phase corev1.PodPhase) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "uyuni", Labels: map[string]string{"job-name": migrationJobName}},
+			Status:     corev1.PodStatus{Phase: phase},
+		}
+	}
+
+	setFakeClients(t, []runtime.Object{pod("uyuni-migration-failed", corev1.PodFailed)})
+	if _, err := getMigrationJobOutput("uyuni"); err == nil {
+		t.Error("expected an error without succeeded pod")
+	}
+
+	// The fake clientset returns the same logs for all the pods
+	setFakeClients(t, []runtime.Object{pod("uyuni-migration-failed", corev1.PodFailed), pod("uyuni-migration-ok", corev1.PodSucceeded)})
+	if out, err := getMigrationJobOutput("uyuni"); err != nil || out == "" {
+		t.Errorf("expected the logs of the succeeded pod, got %q, %v", out, err)
+	}
+}
+
+func TestDeleteMigrationJob(t *testing.T) {
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: migrationJobName, Namespace: "uyuni"}}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name: migrationJobName + "-abcde", Namespace: "uyuni", Labels: map[string]string{"job-name": migrationJobName},
+	}}
+	tests := []struct {
+		name        string
+		objects     []runtime.Object
+		expectedErr bool
+	}{
+		{"previous job", []runtime.Object{job}, false},
+		{"no previous job", []runtime.Object{}, false},
+		// The fake clientset doesn't collect the pods of the deleted jobs
+		{"pod not removed", []runtime.Object{job, pod}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := setFakeClients(t, test.objects)
+
+			err := deleteMigrationJob("uyuni", 200*time.Millisecond)
+			if test.expectedErr && err == nil {
+				t.Error("expected an error")
+			} else if !test.expectedErr && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+			jobs, err := client.BatchV1().Jobs("uyuni").List(context.Background(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(jobs.Items) != 0 {
+				t.Errorf("the migration job was not deleted")
+			}
+		})
+	}
+}
+
+func TestGetLocalNode(t *testing.T) {
+	node := func(name string, hostname string) runtime.Object {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+			Name: name, Labels: map[string]string{"kubernetes.io/hostname": hostname},
+		}}
+	}
+	tests := []struct {
+		name        string
+		nodes       []runtime.Object
+		expected    string
+		expectedErr bool
+	}{
+		{"single node", []runtime.Object{node("other.example.com", "other")}, "other", false},
+		{"matching hostname", []runtime.Object{node("node1.example.com", "node1"), node("uyuni.example.com", "uyuni")}, "uyuni", false},
+		{"matching node name", []runtime.Object{node("node1", "node1-host"), node("uyuni", "uyuni-host")}, "uyuni-host", false},
+		{"no matching node", []runtime.Object{node("node1", "node1"), node("node2", "node2")}, "", true},
+	}
+
+	previousGetHostname := getHostname
+	getHostname = func() (string, error) { return "uyuni.example.com", nil }
+	defer func() { getHostname = previousGetHostname }()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			setFakeClients(t, test.nodes)
+
+			hostname, err := getLocalNode()
+			if test.expectedErr && err == nil {
+				t.Error("expected an error")
+			} else if !test.expectedErr && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+			if hostname != test.expected {
+				t.Errorf("expected %q node, got %q", test.expected, hostname)
+			}
+		})
+	}
+}
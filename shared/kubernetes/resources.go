@@ -0,0 +1,42 @@
+package kubernetes
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// The cert-manager and trust-manager resources are read with the dynamic client
+// as their types are not part of the kubernetes API.
+var (
+	issuerResource        = schema.GroupVersionResource{Group: "cert-manager.io", Version: "v1", Resource: "issuers"}
+	clusterIssuerResource = schema.GroupVersionResource{Group: "cert-manager.io", Version: "v1", Resource: "clusterissuers"}
+	certificateResource   = schema.GroupVersionResource{Group: "cert-manager.io", Version: "v1", Resource: "certificates"}
+	bundleResource        = schema.GroupVersionResource{Group: "trust.cert-manager.io", Version: "v1alpha1", Resource: "bundles"}
+)
+
+// getResource returns a custom resource, an empty namespace meaning a cluster-wide resource.
+func getResource(resource schema.GroupVersionResource, namespace string, name string) (*unstructured.Unstructured, error) {
+	client, err := getDynamicClient()
+	if err != nil {
+		return nil, err
+	}
+	if namespace == "" {
+		return client.Resource(resource).Get(context.Background(), name, metav1.GetOptions{})
+	}
+	return client.Resource(resource).Namespace(namespace).Get(context.Background(), name, metav1.GetOptions{})
+}
+
+// isResourceReady returns whether the Ready condition of a cert-manager resource is true.
+func isResourceReady(object *unstructured.Unstructured) bool {
+	conditions, _, _ := unstructured.NestedSlice(object.Object, "status", "conditions")
+	for _, condition := range conditions {
+		fields, ok := condition.(map[string]interface{})
+		if ok && fields["type"] == "Ready" {
+			return fields["status"] == "True"
+		}
+	}
+	return false
+}
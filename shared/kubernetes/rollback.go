@@ -0,0 +1,63 @@
+package kubernetes
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+)
+
+// upgradeTransaction records the helm release revision before an upgrade to be able to restore it.
+type upgradeTransaction struct {
+	namespace        string
+	backend          types.Backend
+	previousRevision int
+	verbose          bool
+	// dataChanged is set once the upgrade started changing the data in the persistent volumes.
+	dataChanged bool
+}
+
+// beginUpgrade records the current revision of the uyuni helm release.
+func beginUpgrade(namespace string, backend types.Backend, verbose bool) (*upgradeTransaction, error) {
+	status, err := getReleaseStatus(namespace, HELM_APP_NAME)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Previous %s helm release revision: %d\n", HELM_APP_NAME, status.Version)
+
+	return &upgradeTransaction{
+		namespace:        namespace,
+		backend:          backend,
+		previousRevision: status.Version,
+		verbose:          verbose,
+	}, nil
+}
+
+// beginDataChanges records that the next steps change the data in the persistent volumes.
+func (t *upgradeTransaction) beginDataChanges() {
+	t.dataChanged = true
+}
+
+// rollback restores the previous helm release revision and returns the error to report.
+// The persistent volumes are not restored: if the data was changed, the error asks to restore a backup.
+func (t *upgradeTransaction) rollback(cause error) error {
+	log.Printf("%s, rolling back to %s helm release revision %d\n", cause, HELM_APP_NAME, t.previousRevision)
+
+	dataWarning := ""
+	if t.dataChanged {
+		dataWarning = "\nthe data in the persistent volumes was not rolled back and the database may be partially upgraded: " +
+			"restore a backup with uyuniadm restore"
+	}
+
+	if err := rollbackRelease(t.namespace, HELM_APP_NAME, t.previousRevision, t.verbose); err != nil {
+		return fmt.Errorf("%w\n%s%s", cause, err, dataWarning)
+	}
+	if err := t.backend.WaitReady(); err != nil {
+		return fmt.Errorf("%w\nrestored server didn't start: %s%s", cause, err, dataWarning)
+	}
+
+	if t.dataChanged {
+		return fmt.Errorf("upgrade failed, helm release rolled back to revision %d: %w%s", t.previousRevision, cause, dataWarning)
+	}
+	return fmt.Errorf("upgrade failed, server restored to helm release revision %d: %w", t.previousRevision, cause)
+}
@@ -0,0 +1,327 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	caSecretName     = "uyuni-ca"
+	serverSecretName = "uyuni-cert"
+)
+
+// expiryWarningDelay is how long before the expiration date of a certificate a warning is shown.
+const expiryWarningDelay = 30 * 24 * time.Hour
+
+// checkExistingCertificates ensures the CA and server secrets needed by the server are present and valid.
+// If the certificate files are passed, they are imported in the secrets first.
+func checkExistingCertificates(viper *viper.Viper, fqdn string) error {
+	namespace := viper.GetString("helm.uyuni.namespace")
+
+	if viper.GetString("cert.ca") != "" || viper.GetString("cert.server.cert") != "" {
+		if err := importCertificates(viper, namespace, fqdn, true); err != nil {
+			return err
+		}
+	}
+
+	caData, err := getSecretData(namespace, caSecretName)
+	if err != nil {
+		return err
+	}
+	serverData, err := getSecretData(namespace, serverSecretName)
+	if err != nil {
+		return err
+	}
+	if caData == nil || serverData == nil {
+		return &utils.ConfigError{Message: fmt.Sprintf("%s and %s secrets are required in %s namespace when using existing certificates.\n"+
+			"Create them or pass the --cert-ca, --cert-server-cert and --cert-server-key files to import",
+			caSecretName, serverSecretName, namespace)}
+	}
+
+	caCert := caData["ca.crt"]
+	if len(caCert) == 0 {
+		caCert = caData["tls.crt"]
+	}
+	ca, err := utils.ParseCertificates(caCert)
+	if err != nil {
+		return fmt.Errorf("invalid CA certificate in %s secret: %w", caSecretName, err)
+	}
+	if len(ca) == 0 {
+		return fmt.Errorf("no CA certificate found in %s secret", caSecretName)
+	}
+
+	server, err := utils.ParseCertificates(serverData["tls.crt"])
+	if err != nil {
+		return fmt.Errorf("invalid certificate in %s secret: %w", serverSecretName, err)
+	}
+	if len(server) == 0 {
+		return fmt.Errorf("no certificate found in %s secret", serverSecretName)
+	}
+	if err := utils.VerifyCertificate(ca, server[1:], server[0], fqdn, viper.GetStringSlice("cert.cname")); err != nil {
+		return fmt.Errorf("invalid certificate in %s secret: %w", serverSecretName, err)
+	}
+	if _, err := tls.X509KeyPair(serverData["tls.crt"], serverData["tls.key"]); err != nil {
+		return fmt.Errorf("key doesn't match certificate in %s secret: %w", serverSecretName, err)
+	}
+
+	for _, certificate := range append(ca, server...) {
+		warnIfExpiring(certificate)
+	}
+	return nil
+}
+
+func warnIfExpiring(certificate *x509.Certificate) {
+	if time.Until(certificate.NotAfter) < expiryWarningDelay {
+		log.Printf("Warning: certificate %s expires on %s\n", certificate.Subject.CommonName,
+			certificate.NotAfter.Format(time.RFC3339))
+	}
+}
+
+// getSecretData returns the data of a secret or nil if there is no such secret.
+func getSecretData(namespace string, name string) (map[string][]byte, error) {
+	client, err := getClientset()
+	if err != nil {
+		return nil, err
+	}
+	secret, err := client.CoreV1().Secrets(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s secret: %w", name, err)
+	}
+	if secret.Data == nil {
+		return map[string][]byte{}, nil
+	}
+	return secret.Data, nil
+}
+
+// secretExists returns whether any of the secrets exists.
+func secretExists(namespace string, names ...string) (bool, error) {
+	for _, name := range names {
+		data, err := getSecretData(namespace, name)
+		if err != nil {
+			return false, err
+		}
+		if data != nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// importCertificates validates the certificate files and stores them in the secrets expected by the server.
+// If confirm is true, existing secrets are only replaced after confirmation.
+func importCertificates(viper *viper.Viper, namespace string, fqdn string, confirm bool) error {
+	caPath := viper.GetString("cert.ca")
+	certPath := viper.GetString("cert.server.cert")
+	keyPath := viper.GetString("cert.server.key")
+	intermediates := viper.GetStringSlice("cert.intermediate")
+	if caPath == "" || certPath == "" || keyPath == "" {
+		return &utils.ConfigError{Message: "--cert-ca, --cert-server-cert and --cert-server-key are all required to import certificates"}
+	}
+	if err := utils.CheckCertificateFiles(caPath, intermediates, certPath, keyPath, fqdn, viper.GetStringSlice("cert.cname")); err != nil {
+		return err
+	}
+
+	if confirm {
+		exists, err := secretExists(namespace, caSecretName, serverSecretName)
+		if err != nil {
+			return err
+		}
+		if exists {
+			replace, err := utils.AskConfirmation("The certificate secrets already exist, replace them with the files?")
+			if err != nil {
+				return err
+			}
+			if !replace {
+				log.Println("Keeping the existing certificate secrets")
+				return nil
+			}
+		}
+	}
+
+	log.Println("Importing certificates in secrets")
+	ca, err := readFile(caPath)
+	if err != nil {
+		return err
+	}
+	// The server certificate needs to be followed by the intermediate CA certificates
+	var chain []byte
+	for _, path := range append([]string{certPath}, intermediates...) {
+		content, err := readFile(path)
+		if err != nil {
+			return err
+		}
+		chain = append(chain, content...)
+		if len(content) > 0 && content[len(content)-1] != '\n' {
+			chain = append(chain, '\n')
+		}
+	}
+	key, err := readFile(keyPath)
+	if err != nil {
+		return err
+	}
+
+	if err := applySecret(namespace, caSecretName, "Opaque", map[string][]byte{"ca.crt": ca}); err != nil {
+		return err
+	}
+	return applySecret(namespace, serverSecretName, "kubernetes.io/tls", map[string][]byte{
+		"ca.crt":  ca,
+		"tls.crt": chain,
+		"tls.key": key,
+	})
+}
+
+func readFile(path string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return content, nil
+}
+
+// applySecret creates or replaces a secret.
+func applySecret(namespace string, name string, secretType string, data map[string][]byte) error {
+	client, err := getClientset()
+	if err != nil {
+		return err
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Type:       corev1.SecretType(secretType),
+		Data:       data,
+	}
+
+	secrets := client.CoreV1().Secrets(namespace)
+	_, err = secrets.Create(context.Background(), secret, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		_, err = secrets.Update(context.Background(), secret, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create %s secret: %w", name, err)
+	}
+	return nil
+}
+
+// isManagedCertificate returns whether a cert-manager certificate generates the secret.
+func isManagedCertificate(namespace string, name string) bool {
+	_, err := getResource(certificateResource, namespace, name)
+	return err == nil
+}
+
+// renewCertificates imports the certificate files in the secrets or gets cert-manager to issue new ones.
+// The CA configmap and the CA in the server container are then refreshed and the services restarted.
+func renewCertificates(viper *viper.Viper, globalFlags *types.GlobalFlags, fqdn string, rotateCa bool) error {
+	namespace := viper.GetString("helm.uyuni.namespace")
+
+	if viper.GetString("cert.server.cert") != "" {
+		if err := importCertificates(viper, namespace, fqdn, false); err != nil {
+			return err
+		}
+	} else {
+		if !isManagedCertificate(namespace, serverSecretName) {
+			return &utils.ConfigError{Message: fmt.Sprintf("no %s certificate managed by cert-manager: pass the new certificate files "+
+				"with --cert-ca, --cert-server-cert and --cert-server-key", serverSecretName)}
+		}
+
+		// Deleting the secrets gets cert-manager to issue new certificates
+		if rotateCa {
+			if !isManagedCertificate(namespace, caSecretName) {
+				return &utils.ConfigError{Message: "the CA is not managed by uyuniadm, rotate it on its issuer and import it with --cert-ca"}
+			}
+			log.Println("Issuing new CA certificate")
+			if err := reissueSecret(namespace, caSecretName, globalFlags.Timeouts.WithDefaults().Certificates, globalFlags.Verbose); err != nil {
+				return err
+			}
+		}
+		log.Println("Issuing new server certificate")
+		if err := reissueSecret(namespace, serverSecretName, globalFlags.Timeouts.WithDefaults().Certificates, globalFlags.Verbose); err != nil {
+			return err
+		}
+	}
+
+	caData, err := getSecretData(namespace, caSecretName)
+	if err != nil {
+		return err
+	}
+	if caData == nil {
+		return fmt.Errorf("no %s secret in %s namespace", caSecretName, namespace)
+	}
+
+	if err := SyncCaConfigMap(namespace, globalFlags.Verbose); err != nil {
+		return err
+	}
+
+	backend := NewBackend(globalFlags)
+	command := fmt.Sprintf("cat >%s && cp %s %s && update-ca-certificates", utils.CaCertPath, utils.CaCertPath, utils.PubCaCertPath)
+	if err := utils.RunServerCmd(backend, command, bytes.NewReader(caData["ca.crt"]),
+		"failed to update the CA certificate in the server"); err != nil {
+		return err
+	}
+
+	log.Println("Restarting the services")
+	return utils.RunServerCmd(backend, utils.RestartSslServicesCommand, nil, "failed to restart the services")
+}
+
+// reissueSecret removes a secret generated by cert-manager and waits for it to be generated again.
+func reissueSecret(namespace string, name string, timeout time.Duration, verbose bool) error {
+	client, err := getClientset()
+	if err != nil {
+		return err
+	}
+	if verbose {
+		log.Printf("Deleting secret %s in namespace %s\n", name, namespace)
+	}
+	if err := client.CoreV1().Secrets(namespace).Delete(context.Background(), name, metav1.DeleteOptions{}); err != nil {
+		return fmt.Errorf("failed to remove %s secret: %w", name, err)
+	}
+
+	secrets := client.CoreV1().Secrets(namespace)
+	secretsWatcher := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = "metadata.name=" + name
+			return secrets.List(context.Background(), options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = "metadata.name=" + name
+			return secrets.Watch(context.Background(), options)
+		},
+	}
+	return watchUntil("cert-manager to issue the "+name+" secret again", secretsWatcher, &corev1.Secret{}, timeout,
+		func(event watch.Event) (bool, error) {
+			secret, ok := event.Object.(*corev1.Secret)
+			return ok && secret.Name == name && event.Type != watch.Deleted, nil
+		})
+}
+
+func getCertificates(namespace string) ([]byte, []byte, error) {
+	caData, err := getSecretData(namespace, caSecretName)
+	if err != nil {
+		return nil, nil, err
+	}
+	serverData, err := getSecretData(namespace, serverSecretName)
+	if err != nil {
+		return nil, nil, err
+	}
+	if caData == nil || serverData == nil {
+		return nil, nil, fmt.Errorf("%s and %s secrets are missing in %s namespace", caSecretName, serverSecretName, namespace)
+	}
+	return caData["ca.crt"], serverData["tls.crt"], nil
+}
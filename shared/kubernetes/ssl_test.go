@@ -0,0 +1,22 @@
+package kubernetes
+
+import "testing"
+
+func TestIsManagedCertificate(t *testing.T) {
+	setFakeClients(t, nil, newResource("cert-manager.io/v1", "Certificate", "uyuni", serverSecretName, readyStatus("True")))
+
+	tests := []struct {
+		namespace string
+		name      string
+		expected  bool
+	}{
+		{"uyuni", serverSecretName, true},
+		{"uyuni", caSecretName, false},
+		{"other", serverSecretName, false},
+	}
+	for _, test := range tests {
+		if actual := isManagedCertificate(test.namespace, test.name); actual != test.expected {
+			t.Errorf("%s/%s: expected %t, got %t", test.namespace, test.name, test.expected, actual)
+		}
+	}
+}
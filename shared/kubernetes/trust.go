@@ -0,0 +1,205 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/spf13/viper"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+const caConfigMapName = "uyuni-ca"
+
+// setupCaConfigMap gets the uyuni-ca configmap to contain the CA certificate of the uyuni-ca secret
+// as the container shouldn't have the CA secret.
+//
+// If trust-manager is available a Bundle keeps the configmap in sync with the secret,
+// otherwise the configmap is only a copy and needs to be synchronized after each CA change.
+// trust-manager reads the secrets from a single namespace: other namespaces need the copy too.
+func setupCaConfigMap(viper *viper.Viper, globalFlags *types.GlobalFlags) error {
+	namespace := viper.GetString("helm.uyuni.namespace")
+
+	if !isDeploymentReady("", "trust-manager") && viper.GetBool("helm.trustmanager.install") {
+		if err := installTrustManager(viper, globalFlags); err != nil {
+			return err
+		}
+	}
+
+	if !isDeploymentReady("", "trust-manager") {
+		log.Println("trust-manager is not available, uyuni-ca configmap will need to be synchronized after each CA change")
+		return SyncCaConfigMap(namespace, globalFlags.Verbose)
+	}
+
+	if target := getCaBundleNamespace(); target != "" && target != namespace {
+		log.Printf("trust-manager bundle already used for %s namespace, uyuni-ca configmap will need to be synchronized after each CA change\n",
+			target)
+		return SyncCaConfigMap(namespace, globalFlags.Verbose)
+	}
+
+	log.Println("Creating trust-manager bundle for the CA certificate")
+	// trust-manager doesn't take over a configmap it didn't create
+	client, err := getClientset()
+	if err != nil {
+		return err
+	}
+	configMaps := client.CoreV1().ConfigMaps(namespace)
+	if !hasCaBundle(namespace) {
+		err := configMaps.Delete(context.Background(), caConfigMapName, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to remove the uyuni-ca configmap: %w", err)
+		}
+	}
+
+	model := struct {
+		Name       string
+		SecretName string
+		Namespace  string
+	}{
+		Name:       caConfigMapName,
+		SecretName: caSecretName,
+		Namespace:  namespace,
+	}
+	if err := applyTemplate(bundleTemplate, model, "failed to create the trust-manager bundle", globalFlags.Verbose); err != nil {
+		return err
+	}
+
+	// Wait for the configmap to be created
+	configMapsWatcher := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = "metadata.name=" + caConfigMapName
+			return configMaps.List(context.Background(), options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = "metadata.name=" + caConfigMapName
+			return configMaps.Watch(context.Background(), options)
+		},
+	}
+	return watchUntil("trust-manager to create the uyuni-ca configmap", configMapsWatcher, &corev1.ConfigMap{},
+		globalFlags.Timeouts.WithDefaults().Certificates, func(event watch.Event) (bool, error) {
+			configMap, ok := event.Object.(*corev1.ConfigMap)
+			return ok && configMap.Name == caConfigMapName && event.Type != watch.Deleted, nil
+		})
+}
+
+// installTrustManager installs trust-manager using helm.
+// It needs cert-manager to be installed first.
+func installTrustManager(viper *viper.Viper, globalFlags *types.GlobalFlags) error {
+	log.Println("Installing trust-manager")
+	repo := ""
+	chart := viper.GetString("helm.trustmanager.chart")
+	version := viper.GetString("helm.trustmanager.version")
+	namespace := viper.GetString("helm.trustmanager.namespace")
+
+	// trust-manager only reads the bundle sources from its trust namespace
+	values, err := helmValues(nil, viper.GetString("helm.trustmanager.values"), map[string]interface{}{
+		"app":          map[string]interface{}{"trust": map[string]interface{}{"namespace": viper.GetString("helm.uyuni.namespace")}},
+		"commonLabels": installedByLabels(),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Use upstream chart if nothing defined
+	if chart == "" {
+		repo = "https://charts.jetstack.io"
+		chart = "trust-manager"
+	}
+	// The installedby label will be used to only uninstall what we installed
+	if err := helmInstall(globalFlags, namespace, repo, "trust-manager", chart, version, values); err != nil {
+		return err
+	}
+
+	return waitForDeployment(namespace, "trust-manager", "trust-manager", globalFlags.Timeouts.WithDefaults())
+}
+
+// getCaBundleNamespace returns the namespace targeted by the CA trust-manager bundle
+// or an empty string if there is no such bundle.
+func getCaBundleNamespace() string {
+	bundle, err := getResource(bundleResource, "", caConfigMapName)
+	if err != nil {
+		return ""
+	}
+	target, _, _ := unstructured.NestedString(bundle.Object, "spec", "target", "namespaceSelector", "matchLabels",
+		"kubernetes.io/metadata.name")
+	return target
+}
+
+// deleteCaBundle removes the CA trust-manager bundle.
+func deleteCaBundle() error {
+	client, err := getDynamicClient()
+	if err != nil {
+		return err
+	}
+	return client.Resource(bundleResource).Delete(context.Background(), caConfigMapName, metav1.DeleteOptions{})
+}
+
+// hasCaBundle returns whether the CA configmap of namespace is managed by a trust-manager bundle.
+func hasCaBundle(namespace string) bool {
+	target := getCaBundleNamespace()
+	return target != "" && target == namespace
+}
+
+// SyncCaConfigMap copies the CA certificate from the uyuni-ca secret to the uyuni-ca configmap.
+// This is only needed when the configmap isn't managed by trust-manager.
+func SyncCaConfigMap(namespace string, verbose bool) error {
+	if hasCaBundle(namespace) {
+		log.Println("uyuni-ca configmap is synchronized by trust-manager, nothing to do")
+		return nil
+	}
+
+	data, err := getSecretData(namespace, caSecretName)
+	if err != nil {
+		return err
+	}
+	if data == nil || len(data["ca.crt"]) == 0 {
+		return fmt.Errorf("no CA certificate in %s secret of %s namespace", caSecretName, namespace)
+	}
+
+	log.Println("Copying CA certificate to uyuni-ca configmap")
+	model := struct {
+		Name      string
+		Namespace string
+		Cert      string
+	}{
+		Name:      caConfigMapName,
+		Namespace: namespace,
+		Cert:      "    " + strings.ReplaceAll(strings.TrimSpace(string(data["ca.crt"])), "\n", "\n    "),
+	}
+	return applyTemplate(caConfigMapTemplate, model, "failed to create the uyuni-ca configmap", verbose)
+}
+
+const bundleTemplate = `apiVersion: trust.cert-manager.io/v1alpha1
+kind: Bundle
+metadata:
+  name: {{ .Name }}
+spec:
+  sources:
+  - secret:
+      name: {{ .SecretName }}
+      key: ca.crt
+  target:
+    configMap:
+      key: ca.crt
+    namespaceSelector:
+      matchLabels:
+        kubernetes.io/metadata.name: {{ .Namespace }}
+`
+
+const caConfigMapTemplate = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{ .Name }}
+  namespace: {{ .Namespace }}
+data:
+  ca.crt: |
+{{ .Cert }}
+`
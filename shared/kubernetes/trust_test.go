@@ -0,0 +1,39 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestHasCaBundle(t *testing.T) {
+	bundle := func(target string) runtime.Object {
+		return newResource("trust.cert-manager.io/v1alpha1", "Bundle", "", caConfigMapName, map[string]interface{}{
+			"spec": map[string]interface{}{
+				"target": map[string]interface{}{
+					"namespaceSelector": map[string]interface{}{
+						"matchLabels": map[string]interface{}{"kubernetes.io/metadata.name": target},
+					},
+				},
+			},
+		})
+	}
+	tests := []struct {
+		name      string
+		resources []runtime.Object
+		expected  bool
+	}{
+		{"bundle for the namespace", []runtime.Object{bundle("uyuni")}, true},
+		{"bundle for another namespace", []runtime.Object{bundle("other")}, false},
+		{"no bundle", nil, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			setFakeClients(t, nil, test.resources...)
+			if actual := hasCaBundle("uyuni"); actual != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package kubernetes
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func uninstallForKubernetes(globalFlags *types.GlobalFlags, dryRun bool) error {
+	namespace := globalFlags.Namespace
+	if namespace == "" {
+		namespace = DefaultNamespace
+	}
+
+	// Uninstall uyuni
+	uninstalled, err := helmUninstall(HELM_APP_NAME, namespace, "", dryRun, globalFlags.Verbose)
+	if err != nil {
+		return err
+	}
+	if uninstalled != "" {
+		// Remove the remaining configmap and secrets
+		if dryRun {
+			log.Printf("Would run kubectl delete -n %s configmap uyuni-ca\n", namespace)
+			log.Printf("Would run kubectl delete -n %s secret uyuni-ca uyuni-cert\n", namespace)
+		} else {
+			deleteServerResources(namespace)
+		}
+	}
+
+	// Remove the trust-manager bundle if it is for this namespace: it is cluster-wide
+	if hasCaBundle(namespace) {
+		if dryRun {
+			log.Printf("Would delete trust-manager bundle %s\n", caConfigMapName)
+		} else {
+			log.Printf("Deleting trust-manager bundle %s\n", caConfigMapName)
+			if err := deleteCaBundle(); err != nil {
+				log.Printf("Failed deleting trust-manager bundle: %s\n", err)
+			}
+		}
+	}
+
+	// Keep trust-manager and cert-manager for the servers in other namespaces
+	if others := getServerNamespaces(namespace); len(others) > 0 {
+		log.Printf("Keeping cert-manager and trust-manager used by the servers in namespaces: %s\n", strings.Join(others, ", "))
+		return nil
+	}
+
+	// Uninstall trust-manager and cert-manager if we installed them
+	if _, err := helmUninstall("trust-manager", "", "installedby=uyuniadm", dryRun, globalFlags.Verbose); err != nil {
+		return err
+	}
+	_, err = helmUninstall("cert-manager", "", "installedby=uyuniadm", dryRun, globalFlags.Verbose)
+	return err
+}
+
+// deleteServerResources deletes the CA configmap and the secrets helm doesn't remove.
+// The failures are only logged as the resources may already be gone.
+func deleteServerResources(namespace string) {
+	client, err := getClientset()
+	if err != nil {
+		log.Printf("Failed deleting config map and secrets: %s\n", err)
+		return
+	}
+	ctx := context.Background()
+
+	log.Printf("Deleting configmap uyuni-ca in namespace %s\n", namespace)
+	if err := client.CoreV1().ConfigMaps(namespace).Delete(ctx, "uyuni-ca", metav1.DeleteOptions{}); err != nil {
+		log.Printf("Failed deleting config map: %s\n", err)
+	}
+
+	for _, secret := range []string{"uyuni-ca", "uyuni-cert"} {
+		log.Printf("Deleting secret %s in namespace %s\n", secret, namespace)
+		if err := client.CoreV1().Secrets(namespace).Delete(ctx, secret, metav1.DeleteOptions{}); err != nil {
+			log.Printf("Failed deleting secret: %s\n", err)
+		}
+	}
+}
+
+// getServerNamespaces returns the namespaces other than exclude containing a server deployment.
+func getServerNamespaces(exclude string) []string {
+	namespaces := []string{}
+	for _, deployment := range findDeployments("", HELM_APP_NAME, "") {
+		if deployment.Namespace != exclude {
+			namespaces = append(namespaces, deployment.Namespace)
+		}
+	}
+	return namespaces
+}
+
+// helmUninstall uninstalls the helm release of a deployment and returns the namespace it was found in.
+// If namespace is empty, the deployment is looked for in all namespaces.
+// The deployment can be filtered with a label selector like installedby=uyuniadm.
+func helmUninstall(deployment string, namespace string, labelSelector string, dryRun bool, verbose bool) (string, error) {
+	deployments := findDeployments(namespace, deployment, labelSelector)
+	if len(deployments) == 0 {
+		log.Printf("No %s deployment found, skipping removal\n", deployment)
+		return "", nil
+	}
+	namespace = deployments[0].Namespace
+	if namespace != "" {
+		if dryRun {
+			log.Printf("Would uninstall helm release %s in namespace %s\n", deployment, namespace)
+		} else {
+			log.Printf("Uninstalling %s\n", deployment)
+			if err := uninstallRelease(namespace, deployment, verbose); err != nil {
+				return "", err
+			}
+		}
+	}
+	return namespace, nil
+}
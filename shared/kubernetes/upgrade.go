@@ -0,0 +1,77 @@
+package kubernetes
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+func upgradeKubernetes(viper *viper.Viper, globalFlags *types.GlobalFlags) error {
+	image := fmt.Sprintf("%s:%s", viper.GetString("image"), viper.GetString("tag"))
+	namespace := viper.GetString("helm.uyuni.namespace")
+	backend := &kubernetesBackend{namespace: namespace, context: globalFlags.KubeContext, timeouts: globalFlags.Timeouts.WithDefaults()}
+
+	// Check the new image is not older than the running one.
+	// Running a pod with the new image also pulls it before upgrading.
+	currentRelease, err := utils.GetServerCmdOutput(backend, utils.ReleaseCommand)
+	if err != nil {
+		return fmt.Errorf("failed to get the version of the running server: %w", err)
+	}
+	log.Printf("Pulling image %s\n", image)
+	newRelease, err := backend.GetImageRelease(viper, image)
+	if err != nil {
+		return err
+	}
+	if err := utils.CheckUpgradeVersions(currentRelease, newRelease); err != nil {
+		return err
+	}
+
+	transaction, err := beginUpgrade(namespace, backend, globalFlags.Verbose)
+	if err != nil {
+		return err
+	}
+
+	log.Println("Upgrading Uyuni")
+	chart := viper.GetString("helm.uyuni.chart")
+	version := viper.GetString("helm.uyuni.version")
+	values, err := helmValues(nil, viper.GetString("helm.uyuni.values"), map[string]interface{}{
+		"images": map[string]interface{}{"server": image},
+	})
+	if err != nil {
+		return transaction.rollback(err)
+	}
+	if err := helmUpgrade(globalFlags, namespace, HELM_APP_NAME, chart, version, values); err != nil {
+		return transaction.rollback(err)
+	}
+
+	// Wait for the new pod to replace the old one
+	timeout := time.Duration(viper.GetInt("rollback.timeout")) * time.Second
+	if err := waitForRollout(namespace, HELM_APP_NAME, timeout); err != nil {
+		return transaction.rollback(fmt.Errorf("new server pod failed to roll out: %w", err))
+	}
+	if err := utils.WaitForServer(backend, timeout, true); err != nil {
+		return transaction.rollback(err)
+	}
+
+	log.Println("Upgrading the database schema")
+	transaction.beginDataChanges()
+	schemaCmd, err := backend.Command("sh", "-c", utils.SchemaUpgradeCommand)
+	if err != nil {
+		return transaction.rollback(err)
+	}
+	out, err := utils.GetRunner().CombinedOutput(schemaCmd)
+	if globalFlags.Verbose {
+		log.Printf("Database schema upgrade output:\n  %s\n", strings.ReplaceAll(strings.TrimSpace(string(out)), "\n", "\n  "))
+	}
+	if err != nil {
+		return transaction.rollback(utils.NewCmdError("failed to upgrade the database schema", schemaCmd, out, err))
+	}
+
+	log.Printf("Server upgraded to %s\n", image)
+	return nil
+}
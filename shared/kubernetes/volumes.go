@@ -0,0 +1,195 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+const pvcTemplate = `{{- range $name, $size := .Volumes }}
+---
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: {{ $name }}
+  namespace: {{ $.Namespace }}
+spec:
+  accessModes:
+    - ReadWriteOnce
+  resources:
+    requests:
+      storage: {{ $size }}
+{{- end }}
+`
+
+// createVolumes creates persistent volume claims for all the server volumes.
+// Existing claims are removed first if force is true, otherwise an error is returned.
+// The uyuni helm release is uninstalled before removing the claims as they can't be removed while the server uses them.
+func createVolumes(namespace string, force bool, timeouts types.Timeouts, verbose bool) error {
+	existing := getExistingVolumes(namespace)
+	if len(existing) > 0 {
+		if !force {
+			return fmt.Errorf("persistent volume claims already exist, use --force to replace them: %s",
+				strings.Join(existing, ", "))
+		}
+		if len(findDeployments(namespace, HELM_APP_NAME, "")) > 0 {
+			log.Printf("Uninstalling the %s helm release to replace its volumes\n", HELM_APP_NAME)
+			if err := uninstallRelease(namespace, HELM_APP_NAME, verbose); err != nil {
+				return err
+			}
+		}
+		if err := deleteVolumes(namespace, existing, verbose); err != nil {
+			return err
+		}
+		// The claims being deleted can't be created again
+		err := utils.WaitFor("the persistent volume claims to be removed", timeouts.Deployment, func() (bool, error) {
+			return len(getExistingVolumes(namespace)) == 0, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return applyVolumes(namespace, []string{}, verbose)
+}
+
+// ensureVolumes creates the persistent volume claims missing for the server volumes.
+func ensureVolumes(namespace string, verbose bool) error {
+	return applyVolumes(namespace, getExistingVolumes(namespace), verbose)
+}
+
+// getExistingVolumes returns the server volumes having a persistent volume claim in the namespace.
+func getExistingVolumes(namespace string) []string {
+	existing := []string{}
+	client, err := getClientset()
+	if err != nil {
+		return existing
+	}
+	for name := range utils.VOLUMES {
+		_, err := client.CoreV1().PersistentVolumeClaims(namespace).Get(context.Background(), name, metav1.GetOptions{})
+		if err == nil {
+			existing = append(existing, name)
+		}
+	}
+	return existing
+}
+
+// deleteVolumes removes persistent volume claims from the namespace.
+func deleteVolumes(namespace string, names []string, verbose bool) error {
+	client, err := getClientset()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if verbose {
+			log.Printf("Deleting persistent volume claim %s in namespace %s\n", name, namespace)
+		}
+		err := client.CoreV1().PersistentVolumeClaims(namespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to remove the existing persistent volume claim %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// applyVolumes creates the persistent volume claims of the server volumes, except the skipped ones.
+func applyVolumes(namespace string, skipped []string, verbose bool) error {
+	sizes := map[string]string{}
+	for name := range utils.VOLUMES {
+		if utils.Contains(skipped, name) {
+			continue
+		}
+		sizes[name] = fmt.Sprintf("%dGi", utils.GetVolumeSize(name))
+	}
+	if len(sizes) == 0 {
+		return nil
+	}
+
+	model := struct {
+		Namespace string
+		Volumes   map[string]string
+	}{
+		Namespace: namespace,
+		Volumes:   sizes,
+	}
+	return applyTemplate(pvcTemplate, model, "failed to create the persistent volume claims", verbose)
+}
+
+const importPodTemplate = `apiVersion: v1
+kind: Pod
+metadata:
+  name: {{ .Name }}
+  namespace: {{ .Namespace }}
+spec:
+  restartPolicy: Never
+  containers:
+  - name: import
+    image: {{ .Image }}
+    command: [ "sleep", "infinity" ]
+    volumeMounts:
+      - mountPath: /mnt
+        name: {{ .Volume }}
+  volumes:
+    - name: {{ .Volume }}
+      persistentVolumeClaim:
+        claimName: {{ .Volume }}
+`
+
+// importVolume extracts a tar archive into a persistent volume claim using a temporary pod.
+func importVolume(namespace string, image string, name string, content io.Reader, timeouts types.Timeouts, verbose bool) error {
+	podName := "uyuni-import-" + name
+	model := struct {
+		Name      string
+		Namespace string
+		Image     string
+		Volume    string
+	}{
+		Name:      podName,
+		Namespace: namespace,
+		Image:     image,
+		Volume:    name,
+	}
+	if err := applyTemplate(importPodTemplate, model, fmt.Sprintf("failed to create pod to import volume %s", name), verbose); err != nil {
+		return err
+	}
+	client, err := getClientset()
+	if err != nil {
+		return err
+	}
+	pods := client.CoreV1().Pods(namespace)
+	defer pods.Delete(context.Background(), podName, metav1.DeleteOptions{})
+
+	if err := waitForPulledImage(namespace, podName, timeouts.ImagePull); err != nil {
+		return err
+	}
+	podsWatcher := newPodsWatcher(client, namespace, "", podName)
+	err = watchUntil("pod "+podName+" to be ready", podsWatcher, &corev1.Pod{}, timeouts.Deployment,
+		func(event watch.Event) (bool, error) {
+			pod, ok := event.Object.(*corev1.Pod)
+			if !ok || pod.Name != podName {
+				return false, nil
+			}
+			if isPodReady(pod) {
+				return true, nil
+			}
+			return false, getPodFailure(pod)
+		})
+	if err != nil {
+		return err
+	}
+
+	cmd := kubectlCommand("exec", "-i", "-n", namespace, podName, "--", "tar", "-C", "/mnt", "-xf", "-")
+	cmd.Stdin = content
+	if out, err := utils.GetRunner().CombinedOutput(cmd); err != nil {
+		return utils.NewCmdError(fmt.Sprintf("failed to import volume %s", name), cmd, out, err)
+	}
+	return nil
+}
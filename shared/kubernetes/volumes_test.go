@@ -0,0 +1,45 @@
+package kubernetes
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func newClaim(namespace string, name string) *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+}
+
+func TestDeleteVolumes(t *testing.T) {
+	client := setFakeClients(t, []runtime.Object{
+		newClaim("uyuni", "var-pgsql"),
+		newClaim("uyuni", "etc-rhn"),
+		newClaim("uyuni", "unrelated"),
+		newClaim("other", "var-pgsql"),
+	})
+
+	existing := getExistingVolumes("uyuni")
+	sort.Strings(existing)
+	if len(existing) != 2 || existing[0] != "etc-rhn" || existing[1] != "var-pgsql" {
+		t.Fatalf("expected [etc-rhn var-pgsql], got %v", existing)
+	}
+
+	// Missing claims are ignored
+	if err := deleteVolumes("uyuni", append(existing, "var-cache"), false); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if remaining := getExistingVolumes("uyuni"); len(remaining) != 0 {
+		t.Errorf("expected no remaining server volume, got %v", remaining)
+	}
+
+	for _, claim := range []struct{ namespace, name string }{{"uyuni", "unrelated"}, {"other", "var-pgsql"}} {
+		_, err := client.CoreV1().PersistentVolumeClaims(claim.namespace).Get(context.Background(), claim.name, metav1.GetOptions{})
+		if err != nil {
+			t.Errorf("%s/%s claim should have been kept: %s", claim.namespace, claim.name, err)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package podman
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// Name is the identifier of the podman backend.
+const Name = "podman"
+
+// ServerContainerName is the name of the server container of the default instance.
+const ServerContainerName = "uyuni-server"
+
+type podmanBackend struct {
+	instance string
+	rootless bool
+	timeouts types.Timeouts
+}
+
+// NewBackend returns the backend managing the server with podman and systemd.
+// The server is the instance of the global flags, running in rootless podman if requested.
+func NewBackend(globalFlags *types.GlobalFlags) types.Backend {
+	return &podmanBackend{instance: globalFlags.Instance, rootless: globalFlags.Rootless, timeouts: globalFlags.Timeouts.WithDefaults()}
+}
+
+func (b *podmanBackend) Name() string {
+	return Name
+}
+
+func (b *podmanBackend) Probe() error {
+	if _, err := exec.LookPath("podman"); err != nil {
+		return err
+	}
+	if err := CheckInstanceName(b.instance); err != nil {
+		return err
+	}
+	return checkRootless(b.rootless)
+}
+
+// GetPodName returns the name of the server container of an instance.
+// A utils.NotRunningError is returned if the container is not running.
+func GetPodName(instance string) (string, error) {
+	containerName := GetContainerName(instance)
+	if out, _ := utils.GetQueryRunner().Output(exec.Command("podman", "ps", "-q", "-f", "name=^"+containerName+"$")); len(out) == 0 {
+		return "", &utils.NotRunningError{Name: containerName, Backend: Name}
+	}
+	return containerName, nil
+}
+
+func (b *podmanBackend) Check(viper *viper.Viper, fqdn string) []types.CheckResult {
+	return checkPodman(b.instance, viper.GetStringSlice("podman.port"), fqdn)
+}
+
+func (b *podmanBackend) Exec(globalFlags *types.GlobalFlags, interactive bool, tty bool, env []string, args ...string) error {
+	podName, err := GetPodName(b.instance)
+	if err != nil {
+		return err
+	}
+
+	commandArgs := []string{"exec"}
+	if interactive {
+		commandArgs = append(commandArgs, "-i")
+	}
+	if tty {
+		commandArgs = append(commandArgs, "-t")
+	}
+	commandArgs = append(commandArgs, podName)
+	commandArgs = append(commandArgs, utils.GetShellArgs(env, args)...)
+
+	return utils.RunInteractiveCmd("podman", commandArgs)
+}
+
+func (b *podmanBackend) Command(args ...string) (*exec.Cmd, error) {
+	podName, err := GetPodName(b.instance)
+	if err != nil {
+		return nil, err
+	}
+	return exec.Command("podman", append([]string{"exec", "-i", podName}, args...)...), nil
+}
+
+func (b *podmanBackend) Copy(globalFlags *types.GlobalFlags, src string, dst string, user string, group string) error {
+	podName, err := GetPodName(b.instance)
+	if err != nil {
+		return err
+	}
+	srcExpanded, dstExpanded := utils.GetCopyPaths(podName, src, dst)
+	if err := utils.RunCmd("podman", []string{"cp", srcExpanded, dstExpanded}, "failed to copy file"); err != nil {
+		return err
+	}
+
+	if chownArgs := utils.GetChownArgs(dst, user, group); len(chownArgs) > 0 {
+		execArgs := append([]string{"exec", podName}, chownArgs...)
+		return utils.RunCmd("podman", execArgs, "failed to change file owner")
+	}
+	return nil
+}
+
+func (b *podmanBackend) Logs(globalFlags *types.GlobalFlags, options types.LogsOptions) error {
+	podName, err := GetPodName(b.instance)
+	if err != nil {
+		return err
+	}
+	args := []string{"logs"}
+	if options.Follow {
+		args = append(args, "-f")
+	}
+	if !options.Since.IsZero() {
+		args = append(args, "--since", options.Since.Format(time.RFC3339))
+	}
+	if options.Tail >= 0 {
+		args = append(args, "--tail", strconv.Itoa(options.Tail))
+	}
+	args = append(args, podName)
+	return utils.RunInteractiveCmd("podman", args)
+}
+
+func (b *podmanBackend) GetImage() (string, error) {
+	return GetServiceImage(b.instance)
+}
+
+func (b *podmanBackend) GetImageRelease(viper *viper.Viper, image string) (string, error) {
+	args := []string{"run", "--rm", "--entrypoint", "sh"}
+	if utils.IsDryRun() {
+		// Don't pull the image with --dry-run: it needs to be available locally
+		args = append(args, "--pull=never")
+	}
+	cmd := exec.Command("podman", append(args, image, "-c", utils.ReleaseCommand)...)
+	out, err := utils.GetQueryRunner().Output(cmd)
+	if err != nil {
+		return "", utils.NewCmdError(fmt.Sprintf("failed to get the server version in image %s", image), cmd, nil, err)
+	}
+	return string(out), nil
+}
+
+func (b *podmanBackend) Status(globalFlags *types.GlobalFlags) error {
+	args := systemctlArgs("status", "--no-pager", GetServiceName(b.instance))
+	return utils.RunInteractiveCmd("systemctl", args)
+}
+
+func (b *podmanBackend) GetContainerStatus() (*types.ContainerStatus, error) {
+	containerName := GetContainerName(b.instance)
+	out, err := utils.GetQueryRunner().Output(exec.Command("podman", "inspect", "--type", "container", containerName))
+	if err != nil {
+		return nil, &utils.NotRunningError{Name: containerName, Backend: Name, Err: err}
+	}
+
+	var containers []struct {
+		ImageName string
+		State     struct {
+			Status    string
+			Running   bool
+			StartedAt time.Time
+		}
+	}
+	if err := json.Unmarshal(out, &containers); err != nil {
+		return nil, fmt.Errorf("failed to parse the %s container state: %w", containerName, err)
+	}
+	if len(containers) == 0 {
+		return nil, &utils.NotRunningError{Name: containerName, Backend: Name}
+	}
+
+	container := containers[0]
+	status := types.ContainerStatus{
+		Name:    containerName,
+		State:   container.State.Status,
+		Running: container.State.Running,
+		Image:   container.ImageName,
+	}
+	if container.State.Running {
+		status.StartedAt = &container.State.StartedAt
+	}
+	return &status, nil
+}
+
+func (b *podmanBackend) Install(viper *viper.Viper, globalFlags *types.GlobalFlags, fqdn string) (map[string]string, error) {
+	return installForPodman(viper, globalFlags, fqdn)
+}
+
+func (b *podmanBackend) CreateVolumes(viper *viper.Viper, globalFlags *types.GlobalFlags, force bool) error {
+	return createVolumes(globalFlags, force)
+}
+
+func (b *podmanBackend) ImportVolume(viper *viper.Viper, globalFlags *types.GlobalFlags, name string, content io.Reader) error {
+	return importVolume(GetVolumeName(b.instance, name), content)
+}
+
+func (b *podmanBackend) Deploy(viper *viper.Viper, globalFlags *types.GlobalFlags, fqdn string) error {
+	return waitForSystemStart(viper, globalFlags)
+}
+
+func (b *podmanBackend) Migrate(viper *viper.Viper, globalFlags *types.GlobalFlags, sourceFqdn string) error {
+	return migrateToPodman(viper, globalFlags, sourceFqdn)
+}
+
+func (b *podmanBackend) Upgrade(viper *viper.Viper, globalFlags *types.GlobalFlags) error {
+	return upgradePodman(viper, globalFlags)
+}
+
+func (b *podmanBackend) RenewCertificates(viper *viper.Viper, globalFlags *types.GlobalFlags, fqdn string, rotateCa bool) error {
+	return renewCertificates(viper, globalFlags, fqdn, rotateCa)
+}
+
+func (b *podmanBackend) GetCertificates(viper *viper.Viper) ([]byte, []byte, error) {
+	return getCertificates(b)
+}
+
+func (b *podmanBackend) Uninstall(globalFlags *types.GlobalFlags, dryRun bool, purge bool) error {
+	return uninstallForPodman(globalFlags, dryRun, purge)
+}
+
+// WaitReady waits for multi-user systemd target to be reached, at most for the server start timeout.
+func (b *podmanBackend) WaitReady() error {
+	return utils.WaitForServer(b, b.timeouts.ServerStart, true)
+}
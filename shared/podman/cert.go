@@ -0,0 +1,112 @@
+package podman
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/viper"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// sslBuildDir is the folder where rhn-ssl-tool stores the CA and the generated certificates.
+// It is in the root volume and thus kept across container restarts.
+const sslBuildDir = "/root/ssl-build"
+
+const generateCertificatesTemplate = `set -e
+{{- if .RotateCa }}
+rhn-ssl-tool --gen-ca --force --dir={{ .Dir }} --password="$CERT_PASS" \
+    --set-country="{{ .Country }}" --set-state="{{ .State }}" --set-city="{{ .City }}" \
+    --set-org="{{ .Org }}" --set-org-unit="{{ .OrgUnit }}" --set-email="{{ .Email }}" \
+    --set-common-name="{{ .Fqdn }}"
+{{- end }}
+rhn-ssl-tool --gen-server --dir={{ .Dir }} --password="$CERT_PASS" --set-hostname="{{ .Fqdn }}" \
+    {{- range .Cnames }}
+    --set-cname="{{ . }}" \
+    {{- end }}
+    --set-country="{{ .Country }}" --set-state="{{ .State }}" --set-city="{{ .City }}" \
+    --set-org="{{ .Org }}" --set-org-unit="{{ .OrgUnit }}" --set-email="{{ .Email }}"
+SERVER_DIR=$(dirname $(ls -t {{ .Dir }}/*/server.crt | head -n 1))
+mgr-ssl-cert-setup --root-ca-file={{ .Dir }}/RHN-ORG-TRUSTED-SSL-CERT \
+    --server-cert-file=$SERVER_DIR/server.crt --server-key-file=$SERVER_DIR/server.key
+`
+
+// renewCertificates generates new certificates in the container using rhn-ssl-tool or imports the
+// existing ones and deploys them with mgr-ssl-cert-setup.
+// mgr-ssl-cert-setup also updates the CA in the trust anchors and in the srv-www-pub volume.
+func renewCertificates(viper *viper.Viper, globalFlags *types.GlobalFlags, fqdn string, rotateCa bool) error {
+	backend := NewBackend(globalFlags)
+
+	var script string
+	if viper.GetString("cert.server.cert") != "" {
+		log.Println("Importing the certificates")
+		if err := checkExistingCertificates(viper, fqdn); err != nil {
+			return err
+		}
+		env := map[string]string{}
+		if err := copyExistingCertificates(viper, globalFlags, env); err != nil {
+			return err
+		}
+		script = fmt.Sprintf("mgr-ssl-cert-setup --root-ca-file=%s --server-cert-file=%s --server-key-file=%s && rm -rf %s",
+			env["CA_CERT"], env["SERVER_CERT"], env["SERVER_KEY"], certsDir)
+	} else {
+		if rotateCa {
+			log.Println("Generating new CA and server certificates")
+		} else {
+			log.Println("Generating new server certificate")
+		}
+		model := struct {
+			RotateCa bool
+			Dir      string
+			Fqdn     string
+			Cnames   []string
+			Country  string
+			State    string
+			City     string
+			Org      string
+			OrgUnit  string
+			Email    string
+		}{
+			RotateCa: rotateCa,
+			Dir:      sslBuildDir,
+			Fqdn:     fqdn,
+			Cnames:   viper.GetStringSlice("cert.cname"),
+			Country:  viper.GetString("cert.country"),
+			State:    viper.GetString("cert.state"),
+			City:     viper.GetString("cert.city"),
+			Org:      viper.GetString("cert.org"),
+			OrgUnit:  viper.GetString("cert.ou"),
+			Email:    viper.GetString("cert.email"),
+		}
+		var buf bytes.Buffer
+		t := template.Must(template.New("certificates").Parse(generateCertificatesTemplate))
+		if err := t.Execute(&buf, model); err != nil {
+			return fmt.Errorf("failed to generate certificates script: %w", err)
+		}
+		// The password is passed in the script to avoid it being visible in the podman command line
+		script = fmt.Sprintf("CERT_PASS='%s'\n%s", strings.ReplaceAll(viper.GetString("cert.password"), "'", "'\\''"),
+			buf.String())
+	}
+
+	if err := utils.RunServerCmd(backend, "bash -s", strings.NewReader(script), "failed to deploy the certificates"); err != nil {
+		return err
+	}
+
+	log.Println("Restarting the services")
+	return utils.RunServerCmd(backend, utils.RestartSslServicesCommand, nil, "failed to restart the services")
+}
+
+func getCertificates(backend types.Backend) ([]byte, []byte, error) {
+	ca, err := utils.GetServerCmdOutput(backend, "cat "+utils.CaCertPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	server, err := utils.GetServerCmdOutput(backend, "cat "+utils.ServerCertPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return []byte(ca), []byte(server), nil
+}
@@ -0,0 +1,92 @@
+package podman
+
+import (
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+func checkPodman(instance string, portMappings []string, fqdn string) []types.CheckResult {
+	results := []types.CheckResult{
+		utils.CheckBinary("podman", true),
+		utils.CheckBinary("systemctl", true),
+		utils.CheckBinary("timedatectl", false),
+	}
+
+	if fqdn != "" {
+		results = append(results, utils.CheckFqdn(fqdn)...)
+	}
+
+	results = append(results, checkCgroup(), checkSelinux(), checkLinger())
+	results = append(results, checkVolumesSpace(instance)...)
+
+	ports, err := GetExposedPorts(portMappings)
+	if err != nil {
+		return append(results, types.CheckResult{Name: "ports", Status: types.CheckFail, Message: err.Error()})
+	}
+	results = append(results, checkRootlessPorts(ports))
+	for _, port := range ports {
+		hostPort := strings.Split(port, ":")[0]
+		results = append(results, utils.CheckPort("tcp", hostPort))
+		// TFTP uses UDP
+		if strings.HasSuffix(port, ":69") {
+			results = append(results, utils.CheckPort("udp", hostPort))
+		}
+	}
+
+	return results
+}
+
+func checkCgroup() types.CheckResult {
+	result := types.CheckResult{Name: "cgroup v2", Status: types.CheckPass, Message: "enabled"}
+	if _, err := os.Stat("/sys/fs/cgroup/cgroup.controllers"); err != nil {
+		result.Status = types.CheckWarn
+		result.Message = "cgroup v1 detected, systemd in the container works best with cgroup v2"
+	}
+	return result
+}
+
+func checkSelinux() types.CheckResult {
+	result := types.CheckResult{Name: "SELinux", Status: types.CheckPass, Message: "disabled"}
+	if content, err := os.ReadFile("/sys/fs/selinux/enforce"); err == nil {
+		if strings.TrimSpace(string(content)) == "1" {
+			result.Status = types.CheckWarn
+			result.Message = "enforcing, the host folders mounted for the migration may not be accessible"
+		} else {
+			result.Message = "permissive"
+		}
+	}
+	return result
+}
+
+// checkVolumesSpace verifies there is enough space for each volume where it is or will be stored.
+func checkVolumesSpace(instance string) []types.CheckResult {
+	results := []types.CheckResult{}
+	out, err := utils.GetQueryRunner().Output(exec.Command("podman", "info", "--format", "{{.Store.VolumePath}}"))
+	if err != nil {
+		return append(results, types.CheckResult{
+			Name: "free space", Status: types.CheckWarn, Message: "failed to get podman volumes path",
+		})
+	}
+	volumesPath := strings.TrimSpace(string(out))
+
+	names := []string{}
+	for name := range utils.VOLUMES {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		path := volumesPath
+		volume := GetVolumeName(instance, name)
+		if mountpoint, err := utils.GetQueryRunner().Output(exec.Command("podman", "volume", "inspect", "--format", "{{.Mountpoint}}", volume)); err == nil {
+			path = strings.TrimSpace(string(mountpoint))
+		}
+		results = append(results, utils.CheckFreeSpace(volume, path, utils.GetVolumeSize(name)))
+	}
+	return results
+}
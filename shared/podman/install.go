@@ -0,0 +1,191 @@
+package podman
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// waitForSystemStart generates and starts the systemd service of the server and waits for the server to start.
+// The failed server services are not reported: they fail until mgr-setup configures them or the database is restored.
+func waitForSystemStart(viper *viper.Viper, globalFlags *types.GlobalFlags) error {
+	// Setup the systemd service configuration options
+	image := fmt.Sprintf("%s:%s", viper.GetString("image"), viper.GetString("tag"))
+	ports, err := GetExposedPorts(viper.GetStringSlice("podman.port"))
+	if err != nil {
+		return err
+	}
+	if result := checkRootlessPorts(ports); result.Status == types.CheckFail {
+		return fmt.Errorf("rootless podman cannot bind some ports: %s", result.Message)
+	}
+	if err := GenerateSystemdService(globalFlags.Instance, viper.GetString("tz"), image,
+		viper.GetStringSlice("podman.arg"), ports); err != nil {
+		return err
+	}
+
+	log.Println("Waiting for the server to start...")
+	// Start the service
+	if err := startService(globalFlags.Instance); err != nil {
+		return err
+	}
+
+	return utils.WaitForServer(NewBackend(globalFlags), globalFlags.Timeouts.WithDefaults().ServerStart, false)
+}
+
+// startService enables and starts the systemd service of an instance.
+// The services generated from Quadlet files cannot be enabled: their [Install] section already does it.
+func startService(instance string) error {
+	serviceName := GetServiceName(instance)
+	quadlet := isQuadletInstalled(instance)
+	if installed, _ := isServiceInstalled(instance); !installed && utils.IsDryRun() {
+		// The units generated with --dry-run have only been printed
+		quadlet = isQuadletSupported()
+	}
+	args := []string{"enable", "--now", serviceName}
+	if quadlet {
+		args = []string{"start", serviceName}
+	}
+	if err := utils.RunCmd("systemctl", systemctlArgs(args...),
+		fmt.Sprintf("failed to enable %s systemd service", serviceName)); err != nil {
+		return err
+	}
+
+	if result := checkLinger(); result.Status != types.CheckPass {
+		log.Printf("Linger %s\n", result.Message)
+	}
+	return nil
+}
+
+// pullImage pulls the server image, giving up after timeout.
+func pullImage(viper *viper.Viper, timeout time.Duration) error {
+	image := fmt.Sprintf("%s:%s", viper.GetString("image"), viper.GetString("tag"))
+	log.Printf("Running podman pull %s\n", image)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "podman", "pull", image)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := utils.GetRunner().Run(cmd); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return &utils.TimeoutError{Description: "image " + image + " to be pulled", Timeout: timeout}
+		}
+		return utils.NewCmdError("failed to pull image", cmd, nil, err)
+	}
+	return nil
+}
+
+func installForPodman(viper *viper.Viper, globalFlags *types.GlobalFlags, fqdn string) (map[string]string, error) {
+	useExisting := viper.GetBool("cert.useexisting")
+	if useExisting {
+		// Fail early rather than after pulling the image and starting the server
+		if err := checkExistingCertificates(viper, fqdn); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := pullImage(viper, globalFlags.Timeouts.WithDefaults().ImagePull); err != nil {
+		return nil, err
+	}
+
+	if err := waitForSystemStart(viper, globalFlags); err != nil {
+		return nil, err
+	}
+
+	env := map[string]string{}
+	if useExisting {
+		if err := copyExistingCertificates(viper, globalFlags, env); err != nil {
+			return nil, err
+		}
+	} else {
+		env["CERT_O"] = viper.GetString("cert.org")
+		env["CERT_OU"] = viper.GetString("cert.ou")
+		env["CERT_CITY"] = viper.GetString("cert.city")
+		env["CERT_STATE"] = viper.GetString("cert.state")
+		env["CERT_COUNTRY"] = viper.GetString("cert.country")
+		env["CERT_EMAIL"] = viper.GetString("cert.email")
+		env["CERT_CNAMES"] = strings.Join(append([]string{fqdn}, viper.GetStringSlice("cert.cname")...), ",")
+		env["CERT_PASS"] = viper.GetString("cert.password")
+	}
+
+	return env, nil
+}
+
+// certsDir is the folder where the existing certificates are copied in the container for the setup.
+// It is not a volume and thus goes away when the container is restarted.
+const certsDir = "/tmp/uyuni-ssl"
+
+func checkExistingCertificates(viper *viper.Viper, fqdn string) error {
+	for _, key := range []string{"cert.ca", "cert.server.cert", "cert.server.key"} {
+		if viper.GetString(key) == "" {
+			return &utils.ConfigError{
+				Message: fmt.Sprintf("--%s is required when using existing certificates", strings.ReplaceAll(key, ".", "-")),
+			}
+		}
+	}
+	return utils.CheckCertificateFiles(viper.GetString("cert.ca"), viper.GetStringSlice("cert.intermediate"),
+		viper.GetString("cert.server.cert"), viper.GetString("cert.server.key"), fqdn, viper.GetStringSlice("cert.cname"))
+}
+
+// copyExistingCertificates copies the existing certificates in the server container
+// and sets the environment variables for the setup script to use them.
+func copyExistingCertificates(viper *viper.Viper, globalFlags *types.GlobalFlags, env map[string]string) error {
+	tmpDir, err := os.MkdirTemp("", "uyuni-ssl-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// The server certificate needs to be followed by the intermediate CA certificates
+	serverCertPath := path.Join(tmpDir, "server.crt")
+	chain := []string{viper.GetString("cert.server.cert")}
+	chain = append(chain, viper.GetStringSlice("cert.intermediate")...)
+	var content []byte
+	for _, certPath := range chain {
+		data, err := os.ReadFile(certPath)
+		if err != nil {
+			return fmt.Errorf("failed to read certificate file %s: %w", certPath, err)
+		}
+		content = append(content, data...)
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			content = append(content, '\n')
+		}
+	}
+	if err := os.WriteFile(serverCertPath, content, 0600); err != nil {
+		return fmt.Errorf("failed to write server certificate chain: %w", err)
+	}
+
+	containerName := GetContainerName(globalFlags.Instance)
+	if err := utils.RunCmd("podman", []string{"exec", containerName, "mkdir", "-p", certsDir},
+		"failed to create the certificates folder in the container"); err != nil {
+		return err
+	}
+
+	files := []struct {
+		variable string
+		src      string
+		name     string
+	}{
+		{"CA_CERT", viper.GetString("cert.ca"), "ca.crt"},
+		{"SERVER_CERT", serverCertPath, "server.crt"},
+		{"SERVER_KEY", viper.GetString("cert.server.key"), "server.key"},
+	}
+	for _, file := range files {
+		dst := path.Join(certsDir, file.name)
+		if err := utils.RunCmd("podman", []string{"cp", file.src, containerName + ":" + dst},
+			"failed to copy "+file.src+" in the container"); err != nil {
+			return err
+		}
+		env[file.variable] = dst
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package podman
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+func TestInstallForPodman(t *testing.T) {
+	running := utils.FakeResult{Prefix: "podman ps -q", Output: "0123456789ab\n"}
+	tests := []struct {
+		name        string
+		instance    string
+		results     []utils.FakeResult
+		expected    []string
+		expectedErr bool
+		unitPath    func(instance string) string
+	}{
+		{
+			name:     "quadlet",
+			results:  []utils.FakeResult{{Prefix: "podman version", Output: "4.9.4-rhel\n"}, running},
+			unitPath: GetQuadletPath,
+			expected: []string{
+				"podman pull registry.example.com/uyuni/server:test",
+				"podman version --format {{.Client.Version}}",
+				systemctlLine("daemon-reload"),
+				systemctlLine("start", "uyuni-server"),
+				"podman ps -q -f name=^uyuni-server$",
+				"podman exec -i uyuni-server systemctl is-active -q multi-user.target",
+			},
+		},
+		{
+			name:     "legacy service of an instance",
+			instance: "test",
+			results:  []utils.FakeResult{{Prefix: "podman version", Output: "4.3.1\n"}, running},
+			unitPath: GetServicePath,
+			expected: []string{
+				"podman pull registry.example.com/uyuni/server:test",
+				"podman version --format {{.Client.Version}}",
+				systemctlLine("daemon-reload"),
+				systemctlLine("enable", "--now", "uyuni-server-test"),
+				"podman ps -q -f name=^uyuni-server-test$",
+				"podman exec -i uyuni-server-test systemctl is-active -q multi-user.target",
+			},
+		},
+		{
+			name:        "failed pull",
+			results:     []utils.FakeResult{{Prefix: "podman pull", Output: "manifest unknown", ExitCode: 125}},
+			expectedErr: true,
+			expected:    []string{"podman pull registry.example.com/uyuni/server:test"},
+		},
+		{
+			// The services fail until mgr-setup configures them
+			name: "failed service before the setup",
+			results: []utils.FakeResult{
+				{Prefix: "podman version", Output: "4.9.4\n"},
+				running,
+				{Prefix: "podman exec -i uyuni-server systemctl list-units", Output: "tomcat.service loaded failed failed Tomcat\n"},
+			},
+			unitPath: GetQuadletPath,
+			expected: []string{
+				"podman pull registry.example.com/uyuni/server:test",
+				"podman version --format {{.Client.Version}}",
+				systemctlLine("daemon-reload"),
+				systemctlLine("start", "uyuni-server"),
+				"podman ps -q -f name=^uyuni-server$",
+				"podman exec -i uyuni-server systemctl is-active -q multi-user.target",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			runner := setUpTestHost(t, test.results)
+
+			viper := viper.New()
+			viper.Set("image", "registry.example.com/uyuni/server")
+			viper.Set("tag", "test")
+			viper.Set("tz", "Europe/Berlin")
+			// Rootless podman can't bind the low ports by default
+			viper.Set("podman.port", []string{"8443:443", "8080:80", "8069:69"})
+			globalFlags := types.GlobalFlags{Instance: test.instance, Timeouts: types.Timeouts{ServerStart: time.Second}}
+
+			_, err := installForPodman(viper, &globalFlags, "server.example.com")
+			if test.expectedErr && err == nil {
+				t.Error("expected an error")
+			} else if !test.expectedErr && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+			assertCommands(t, runner, test.expected)
+
+			if test.unitPath != nil {
+				if _, err := os.Stat(test.unitPath(test.instance)); err != nil {
+					t.Errorf("unit not generated: %s", err)
+				}
+			}
+		})
+	}
+}
+
+func TestDeployFailedService(t *testing.T) {
+	runner := setUpTestHost(t, []utils.FakeResult{
+		{Prefix: "podman version", Output: "4.9.4\n"},
+		{Prefix: "podman ps -q", Output: "0123456789ab\n"},
+		{Prefix: "podman exec -i uyuni-server systemctl list-units", Output: "postgresql.service loaded failed failed PostgreSQL\n"},
+	})
+
+	viper := viper.New()
+	viper.Set("image", "registry.example.com/uyuni/server")
+	viper.Set("tag", "test")
+	viper.Set("podman.port", []string{"8443:443", "8080:80", "8069:69"})
+	globalFlags := types.GlobalFlags{Timeouts: types.Timeouts{ServerStart: time.Second}}
+
+	// The restored volumes have no database yet: its services can't work until it is loaded
+	if err := NewBackend(&globalFlags).Deploy(viper, &globalFlags, "server.example.com"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	assertCommands(t, runner, []string{
+		"podman version --format {{.Client.Version}}",
+		systemctlLine("daemon-reload"),
+		systemctlLine("start", "uyuni-server"),
+		"podman ps -q -f name=^uyuni-server$",
+		"podman exec -i uyuni-server systemctl is-active -q multi-user.target",
+	})
+}
+
+func TestInstallForPodmanDryRun(t *testing.T) {
+	runner, out := setUpDryRunTestHost(t, []utils.FakeResult{{Prefix: "podman version", Output: "4.9.4\n"}})
+
+	viper := viper.New()
+	viper.Set("image", "registry.example.com/uyuni/server")
+	viper.Set("tag", "test")
+	viper.Set("podman.port", []string{"8443:443", "8080:80", "8069:69"})
+
+	// The server never starts: waiting for it would time out
+	globalFlags := types.GlobalFlags{Timeouts: types.Timeouts{ServerStart: time.Hour}}
+	if _, err := installForPodman(viper, &globalFlags, "server.example.com"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	assertCommands(t, runner, []string{
+		"podman version --format {{.Client.Version}}",
+		"podman version --format {{.Client.Version}}",
+	})
+	assertPrinted(t, out.String(), []string{
+		"> Would run: podman pull registry.example.com/uyuni/server:test",
+		"> Would write " + GetQuadletPath("") + ":",
+		"> Would run: " + systemctlLine("daemon-reload"),
+		"> Would run: " + systemctlLine("start", "uyuni-server"),
+		"Would wait for the server services to start",
+	})
+	if _, err := os.Stat(GetQuadletPath("")); err == nil {
+		t.Error("the quadlet file should not be written")
+	}
+}
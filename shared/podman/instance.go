@@ -0,0 +1,90 @@
+package podman
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// Several servers can run on the same podman host, each one being an instance identified by a name.
+// The instance without name uses the historical container, service and volume names.
+
+var instanceRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+
+// CheckInstanceName returns an error if the instance name can't be used in container, service and volume names.
+func CheckInstanceName(instance string) error {
+	if instance != "" && !instanceRegexp.MatchString(instance) {
+		return &utils.ConfigError{
+			Message: fmt.Sprintf("invalid instance name %s: only lower case letters, digits, '_', '.' and '-' are allowed", instance),
+		}
+	}
+	return nil
+}
+
+func addInstanceSuffix(name string, instance string) string {
+	if instance == "" {
+		return name
+	}
+	return name + "-" + instance
+}
+
+// GetContainerName returns the name of the server container of an instance.
+func GetContainerName(instance string) string {
+	return addInstanceSuffix(ServerContainerName, instance)
+}
+
+// GetServiceName returns the name of the systemd service of an instance.
+func GetServiceName(instance string) string {
+	return addInstanceSuffix("uyuni-server", instance)
+}
+
+// GetServicePath returns the path of the systemd service unit of an instance.
+func GetServicePath(instance string) string {
+	return filepath.Join(getServicesDir(), GetServiceName(instance)+".service")
+}
+
+// GetVolumeName returns the name of the podman volume of an instance for one of the utils.VOLUMES
+// or the cgroup volume.
+func GetVolumeName(instance string, name string) string {
+	if instance == "" {
+		return name
+	}
+	return instance + "-" + name
+}
+
+// GetVolumes returns the podman volumes of an instance with their path in the container.
+func GetVolumes(instance string) map[string]string {
+	volumes := map[string]string{}
+	for name, path := range utils.VOLUMES {
+		volumes[GetVolumeName(instance, name)] = path
+	}
+	return volumes
+}
+
+// GetExposedPorts returns the ports to publish as host:container values.
+// The mappings are host:container values remapping some of the default ports, for instance 8443:443.
+func GetExposedPorts(mappings []string) ([]string, error) {
+	hostPorts := map[string]string{}
+	for _, mapping := range mappings {
+		parts := strings.Split(mapping, ":")
+		if len(parts) != 2 || !utils.Contains(defaultPorts, parts[1]) {
+			return nil, &utils.ConfigError{Message: fmt.Sprintf(
+				"invalid port mapping %s: expecting host:container with container port in %s",
+				mapping, strings.Join(defaultPorts, ", "))}
+		}
+		hostPorts[parts[1]] = parts[0]
+	}
+
+	ports := []string{}
+	for _, port := range defaultPorts {
+		hostPort := port
+		if mapped, ok := hostPorts[port]; ok {
+			hostPort = mapped
+		}
+		ports = append(ports, fmt.Sprintf("%s:%s", hostPort, port))
+	}
+	return ports, nil
+}
@@ -0,0 +1,142 @@
+package podman
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/viper"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+func migrateToPodman(viper *viper.Viper, globalFlags *types.GlobalFlags, sourceFqdn string) error {
+	final := viper.GetBool("final")
+	image := viper.GetString("image")
+	tag := viper.GetString("tag")
+	stateDir, err := utils.GetMigrationStateDir(viper.GetString("state.dir"), sourceFqdn)
+	if err != nil {
+		return err
+	}
+
+	sshAuthSocket, err := utils.GetSshAuthSocket()
+	if err != nil {
+		return err
+	}
+
+	// Find ssh config to mount it in the container
+	sshConfigPath, sshKnownhostsPath, err := utils.GetSshPaths()
+	if err != nil {
+		return err
+	}
+
+	scriptDir, err := utils.GenerateMigrationScript(sourceFqdn, false, final)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(scriptDir)
+
+	extraArgs := []string{
+		"-e", "SSH_AUTH_SOCK",
+		"-v", filepath.Dir(sshAuthSocket) + ":" + filepath.Dir(sshAuthSocket),
+		"-v", scriptDir + ":/var/lib/uyuni-tools/",
+		"-v", stateDir + ":" + utils.MigrationStatePath,
+	}
+
+	if _, err := os.Stat(sshConfigPath); err == nil {
+		extraArgs = append(extraArgs, "-v", sshConfigPath+":/root/.ssh/config")
+
+	}
+
+	if _, err := os.Stat(sshKnownhostsPath); err == nil {
+		extraArgs = append(extraArgs, "-v", sshKnownhostsPath+":/root/.ssh/known_hosts")
+	}
+
+	// A final phase interrupted after the synchronization resumes with the next steps
+	if final && utils.IsMigrationStepDone(stateDir, utils.MigrationSynchronizedStep) {
+		log.Println("Server data already synchronized")
+	} else {
+		if final {
+			log.Println("Migrating server")
+		} else {
+			log.Println("Pre-synchronizing server data")
+		}
+		if err := runContainer(addInstanceSuffix("uyuni-migration", globalFlags.Instance), globalFlags.Instance, image, tag,
+			extraArgs, []string{"/var/lib/uyuni-tools/migrate.sh"}, []string{}); err != nil {
+			return err
+		}
+
+		if !final {
+			log.Println("Data pre-synchronized: run again to catch up with the source changes or with --final to finish the migration")
+			return nil
+		}
+		if err := utils.SetMigrationStepDone(stateDir, utils.MigrationSynchronizedStep); err != nil {
+			return err
+		}
+	}
+
+	if utils.IsMigrationStepDone(stateDir, utils.MigrationServiceStep) {
+		log.Printf("%s service already generated\n", GetServiceName(globalFlags.Instance))
+	} else {
+		tz, err := utils.ReadMigrationTimezone(stateDir)
+		if err != nil {
+			return err
+		}
+
+		fullImage := fmt.Sprintf("%s:%s", image, tag)
+
+		ports, err := GetExposedPorts(viper.GetStringSlice("podman.port"))
+		if err != nil {
+			return err
+		}
+		if err := GenerateSystemdService(globalFlags.Instance, tz, fullImage, viper.GetStringSlice("podman.arg"), ports); err != nil {
+			return err
+		}
+		if err := utils.SetMigrationStepDone(stateDir, utils.MigrationServiceStep); err != nil {
+			return err
+		}
+	}
+
+	// Start the service
+	if err := startService(globalFlags.Instance); err != nil {
+		return err
+	}
+
+	os.RemoveAll(stateDir)
+	log.Println("Server migrated")
+	return nil
+}
+
+func runContainer(name string, instance string, image string, tag string, extraArgs []string, cmd []string, env []string) error {
+
+	podmanArgs := append([]string{"run"}, GetCommonParams(name, instance)...)
+	podmanArgs = append(podmanArgs, extraArgs...)
+
+	// Sorted for the command line to be the same for each run
+	volumes := GetVolumes(instance)
+	volumeNames := make([]string, 0, len(volumes))
+	for volume := range volumes {
+		volumeNames = append(volumeNames, volume)
+	}
+	sort.Strings(volumeNames)
+	for _, volume := range volumeNames {
+		podmanArgs = append(podmanArgs, "-v", volume+":"+volumes[volume])
+	}
+
+	podmanArgs = append(podmanArgs, image+":"+tag)
+	podmanArgs = append(podmanArgs, cmd...)
+
+	podmanCmd := exec.Command("podman", podmanArgs...)
+	podmanCmd.Stdout = os.Stdout
+	podmanCmd.Stderr = os.Stderr
+
+	podmanCmd.Env = append(podmanCmd.Environ(), env...)
+	// Wait for the migration to finish and report errors
+	if err := utils.GetRunner().Run(podmanCmd); err != nil {
+		return utils.NewCmdError(fmt.Sprintf("failed to run %s container", name), podmanCmd, nil, err)
+	}
+	return nil
+}
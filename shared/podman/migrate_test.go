@@ -0,0 +1,135 @@
+package podman
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+func TestMigrateToPodman(t *testing.T) {
+	version := utils.FakeResult{Prefix: "podman version", Output: "4.9.4\n"}
+	tests := []struct {
+		name        string
+		final       bool
+		doneSteps   []string
+		results     []utils.FakeResult
+		expected    func(run string) []string
+		expectedErr bool
+		keptState   bool
+	}{
+		{
+			name:      "pre-synchronization",
+			expected:  func(run string) []string { return []string{run} },
+			keptState: true,
+		},
+		{
+			name:    "final",
+			final:   true,
+			results: []utils.FakeResult{version},
+			expected: func(run string) []string {
+				return []string{
+					run,
+					"podman version --format {{.Client.Version}}",
+					systemctlLine("daemon-reload"),
+					systemctlLine("start", "uyuni-server"),
+				}
+			},
+		},
+		{
+			name:      "final resumed after the synchronization",
+			final:     true,
+			doneSteps: []string{utils.MigrationSynchronizedStep},
+			results:   []utils.FakeResult{version},
+			expected: func(run string) []string {
+				return []string{
+					"podman version --format {{.Client.Version}}",
+					systemctlLine("daemon-reload"),
+					systemctlLine("start", "uyuni-server"),
+				}
+			},
+		},
+		{
+			name:      "final resumed after the service generation",
+			final:     true,
+			doneSteps: []string{utils.MigrationSynchronizedStep, utils.MigrationServiceStep},
+			expected: func(run string) []string {
+				return []string{systemctlLine("enable", "--now", "uyuni-server")}
+			},
+		},
+		{
+			name:        "failed synchronization",
+			final:       true,
+			results:     []utils.FakeResult{{Prefix: "podman run", ExitCode: 1}},
+			expected:    func(run string) []string { return []string{run} },
+			expectedErr: true,
+			keptState:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			runner := setUpTestHost(t, test.results)
+			dir := t.TempDir()
+			t.Setenv("HOME", dir)
+			t.Setenv("SSH_AUTH_SOCK", filepath.Join(dir, "agent", "ssh.sock"))
+
+			// The data and steps the migration container and a previous run would have written
+			stateDir := filepath.Join(dir, "state", "source.example.com")
+			if err := os.MkdirAll(stateDir, 0700); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(stateDir, "data"), []byte("Timezone=Europe/Berlin\n"), 0600); err != nil {
+				t.Fatal(err)
+			}
+			for _, step := range test.doneSteps {
+				if err := utils.SetMigrationStepDone(stateDir, step); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			viper := viper.New()
+			viper.Set("image", "registry.example.com/uyuni/server")
+			viper.Set("tag", "test")
+			viper.Set("final", test.final)
+			viper.Set("state.dir", filepath.Join(dir, "state"))
+
+			err := migrateToPodman(viper, &types.GlobalFlags{}, "source.example.com")
+			if test.expectedErr && err == nil {
+				t.Error("expected an error")
+			} else if !test.expectedErr && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+
+			run := "podman run --name uyuni-migration --rm --cap-add NET_RAW --tmpfs /run -v cgroup:/sys/fs/cgroup:rw " +
+				"-e SSH_AUTH_SOCK -v " + filepath.Join(dir, "agent") + ":" + filepath.Join(dir, "agent") +
+				" -v *:/var/lib/uyuni-tools/ -v " + stateDir + ":" + utils.MigrationStatePath + " " +
+				strings.Join(sortedVolumeArgs(), " ") + " registry.example.com/uyuni/server:test /var/lib/uyuni-tools/migrate.sh"
+			assertCommands(t, runner, test.expected(run))
+
+			if _, err := os.Stat(stateDir); (err == nil) != test.keptState {
+				t.Errorf("state folder kept: %t, expected %t", err == nil, test.keptState)
+			}
+		})
+	}
+}
+
+// sortedVolumeArgs returns the podman arguments mounting the volumes of the default instance in name order.
+func sortedVolumeArgs() []string {
+	volumes := GetVolumes("")
+	names := []string{}
+	for name := range volumes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	args := []string{}
+	for _, name := range names {
+		args = append(args, "-v", name+":"+volumes[name])
+	}
+	return args
+}
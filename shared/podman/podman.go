@@ -2,46 +2,75 @@ package podman
 
 import (
 	"fmt"
-	"log"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
 	"text/template"
 
 	"github.com/uyuni-project/uyuni-tools/shared/utils"
 )
 
-const commonArgs = "--name %s --rm --cap-add NET_RAW --tmpfs /run -v cgroup:/sys/fs/cgroup:rw"
+const commonArgs = "--name %s --rm --cap-add NET_RAW --tmpfs /run -v %s:/sys/fs/cgroup:rw"
 
-func GetCommonParams(containerName string) []string {
-	return strings.Split(fmt.Sprintf(commonArgs, containerName), " ")
+// GetCommonParams returns the podman run parameters for a container of an instance.
+func GetCommonParams(containerName string, instance string) []string {
+	return strings.Split(fmt.Sprintf(commonArgs, containerName, GetVolumeName(instance, "cgroup")), " ")
 }
 
-func GetExposedPorts() []string {
-	return []string{"443", "80", "4505", "4506", "69", "25151", "5432", "9100", "9187", "9800"}
-}
+// defaultPorts lists the ports exposed by the server container.
+var defaultPorts = []string{"443", "80", "4505", "4506", "69", "25151", "5432", "9100", "9187", "9800"}
+
+// GenerateSystemdService writes the systemd units of an instance: Quadlet files if podman supports them
+// or a service unit running podman for older versions.
+// The ports are host:container values as returned by GetExposedPorts.
+func GenerateSystemdService(instance string, tz string, image string, podmanArgs []string, ports []string) error {
+	installed, err := isServiceInstalled(instance)
+	if err != nil {
+		return err
+	}
+	if installed {
+		return fmt.Errorf("%s service already present, not overwriting", GetServiceName(instance))
+	}
 
-const ServicePath = "/usr/lib/systemd/system/uyuni-server.service"
+	if isQuadletSupported() {
+		err = generateQuadlet(instance, tz, image, podmanArgs, ports)
+	} else {
+		err = generateLegacyService(instance, tz, image, podmanArgs, ports)
+	}
+	if err != nil {
+		return err
+	}
 
-func GenerateSystemdService(tz string, image string, podmanArgs []string, verbose bool) {
+	return utils.RunCmd("systemctl", systemctlArgs("daemon-reload"), "failed to reload systemd daemon")
+}
 
-	_, err := os.Stat(ServicePath)
-	if err == nil {
-		log.Fatalln("uyuni-server service already present, not overwriting")
-	} else if !os.IsNotExist(err) {
-		log.Fatalf("Failed to stat %s file: %s\n", ServicePath, err)
+// isServiceInstalled returns whether the systemd units of an instance have been generated.
+func isServiceInstalled(instance string) (bool, error) {
+	for _, path := range []string{GetServicePath(instance), GetQuadletPath(instance)} {
+		if _, err := os.Stat(path); err == nil {
+			return true, nil
+		} else if !os.IsNotExist(err) {
+			return false, fmt.Errorf("failed to stat %s file: %w", path, err)
+		}
 	}
+	return false, nil
+}
 
-	file, err := os.OpenFile(ServicePath, os.O_WRONLY|os.O_CREATE, 0555)
-	if err != nil {
-		log.Fatalf("Fail to open uyuni-server.service file: %s\n", err)
+// generateLegacyService writes a service unit running podman for the versions without Quadlet support.
+func generateLegacyService(instance string, tz string, image string, podmanArgs []string, ports []string) error {
+	servicePath := GetServicePath(instance)
+	serviceName := GetServiceName(instance)
+
+	if err := os.MkdirAll(filepath.Dir(servicePath), 0755); err != nil {
+		return fmt.Errorf("failed to create %s folder: %w", filepath.Dir(servicePath), err)
 	}
-	defer file.Close()
 
-	const serviceTemplate = `# uyuni-server.service, generated by uyuniadm
-# Use an uyuni-server.service.d/local.conf file to override
+	const serviceTemplate = `# {{ .Name }}.service, generated by uyuniadm
+# Use an {{ .Name }}.service.d/local.conf file to override
 
 [Unit]
-Description=Uyuni server image container service
+Description=Uyuni server image container service{{ if .Instance }} for {{ .Instance }} instance{{ end }}
 Wants=network.target
 After=network-online.target
 RequiresMountsFor=%t/containers
@@ -51,16 +80,16 @@ Environment=PODMAN_SYSTEMD_UNIT=%n
 Environment=UYUNI_IMAGE={{ .Image }}
 Environment=TZ={{ .Timezone }}
 Restart=on-failure
-ExecStartPre=/bin/rm -f %t/uyuni-server.pid %t/%n.ctr-id
+ExecStartPre=/bin/rm -f %t/{{ .Name }}.pid %t/%n.ctr-id
 ExecStart=/usr/bin/podman run \
-	--conmon-pidfile %t/uyuni-server.pid \
+	--conmon-pidfile %t/{{ .Name }}.pid \
 	--cidfile=%t/%n.ctr-id \
 	--cgroups=no-conmon \
 	--sdnotify=conmon \
 	-d \
 	{{ .Args }} \
 	{{- range .Ports }}
-	-p {{ . }}:{{ . }} \
+	-p {{ . }} \
 	{{- end }}
 	{{- range $name, $path := .Volumes }}
 	-v {{ $name }}:{{ $path }} \
@@ -75,7 +104,7 @@ ExecStopPost=/usr/bin/podman rm \
 	--ignore -t 10 \
 	--cidfile=%t/%n.ctr-id
 
-PIDFile=%t/uyuni-server.pid
+PIDFile=%t/{{ .Name }}.pid
 TimeoutStopSec=180
 TimeoutStartSec=900
 Type=forking
@@ -85,24 +114,76 @@ WantedBy=multi-user.target default.target
 `
 
 	model := struct {
+		Name     string
+		Instance string
 		Volumes  map[string]string
 		Args     string
 		Ports    []string
 		Timezone string
 		Image    string
 	}{
-
-		Volumes:  utils.VOLUMES,
-		Args:     fmt.Sprintf(commonArgs, "uyuni-server") + " " + strings.Join(podmanArgs, " "),
-		Ports:    GetExposedPorts(),
+		Name:     serviceName,
+		Instance: instance,
+		Volumes:  GetVolumes(instance),
+		Args:     strings.Join(append(GetCommonParams(GetContainerName(instance), instance), podmanArgs...), " "),
+		Ports:    ports,
 		Timezone: tz,
 		Image:    image,
 	}
 
 	t := template.Must(template.New("service").Parse(serviceTemplate))
-	if err = t.Execute(file, model); err != nil {
-		log.Fatalf("Failed to generate systemd service unit file: %s\n", err)
+	if err := utils.WriteTemplate(t, servicePath, 0555, model); err != nil {
+		return fmt.Errorf("failed to generate systemd service unit file: %w", err)
+	}
+	return nil
+}
+
+var imageEnvRegexp = regexp.MustCompile(`(?m)^Environment=UYUNI_IMAGE=(.*)$`)
+
+// getImageSetting returns the path of the unit file defining the image of an instance,
+// the regular expression matching the image setting and its prefix.
+func getImageSetting(instance string) (string, *regexp.Regexp, string) {
+	if isQuadletInstalled(instance) {
+		return GetQuadletPath(instance), quadletImageRegexp, "Image="
+	}
+	return GetServicePath(instance), imageEnvRegexp, "Environment=UYUNI_IMAGE="
+}
+
+// GetServiceImage returns the image used in the systemd units of an instance.
+func GetServiceImage(instance string) (string, error) {
+	path, imageRegexp, _ := getImageSetting(instance)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s file: %w", path, err)
+	}
+	matches := imageRegexp.FindSubmatch(content)
+	if matches == nil {
+		return "", fmt.Errorf("no image defined in %s", path)
+	}
+	return string(matches[1]), nil
+}
+
+// UpdateSystemdServiceImage changes the image used in the existing systemd units of an instance.
+// The rest of the units is left untouched.
+func UpdateSystemdServiceImage(instance string, image string) error {
+	path, imageRegexp, prefix := getImageSetting(instance)
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s file: %w", path, err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s file: %w", path, err)
+	}
+
+	if !imageRegexp.Match(content) {
+		return fmt.Errorf("no image defined in %s", path)
+	}
+	content = imageRegexp.ReplaceAll(content, []byte(prefix+image))
+
+	if err = utils.WriteFile(path, content, info.Mode()); err != nil {
+		return err
 	}
 
-	utils.RunCmd("systemctl", []string{"daemon-reload"}, "Failed to reload systemd daemon", verbose)
+	return utils.RunCmd("systemctl", systemctlArgs("daemon-reload"), "failed to reload systemd daemon")
 }
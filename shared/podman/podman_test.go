@@ -0,0 +1,93 @@
+package podman
+
+import (
+	"log"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// setUpTestHost generates the units in temporary folders and records the commands with a FakeRunner.
+func setUpTestHost(t *testing.T, results []utils.FakeResult) *utils.FakeRunner {
+	dir := t.TempDir()
+	previousServicesDir, previousQuadletDir := systemServicesDir, systemQuadletDir
+	systemServicesDir = filepath.Join(dir, "system")
+	systemQuadletDir = filepath.Join(dir, "quadlet")
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+
+	runner := &utils.FakeRunner{Results: results}
+	previousRunner := utils.SetRunner(runner)
+	t.Cleanup(func() {
+		systemServicesDir, systemQuadletDir = previousServicesDir, previousQuadletDir
+		utils.SetRunner(previousRunner)
+	})
+	return runner
+}
+
+// setUpDryRunTestHost is like setUpTestHost with --dry-run: only the queries are recorded.
+// The printed commands and logs are written to the returned builder.
+func setUpDryRunTestHost(t *testing.T, results []utils.FakeResult) (*utils.FakeRunner, *strings.Builder) {
+	runner := setUpTestHost(t, results)
+	out := &strings.Builder{}
+	utils.SetRunner(utils.DryRunRunner{Out: out, Queries: runner})
+
+	previousOutput := log.Writer()
+	log.SetOutput(out)
+	t.Cleanup(func() { log.SetOutput(previousOutput) })
+	return runner, out
+}
+
+// assertPrinted checks the dry run output contains the expected lines in order.
+func assertPrinted(t *testing.T, out string, expected []string) {
+	t.Helper()
+	for _, line := range expected {
+		index := strings.Index(out, line)
+		if index < 0 {
+			t.Errorf("expected %q in the output:\n%s", line, out)
+			return
+		}
+		out = out[index+len(line):]
+	}
+}
+
+// systemctlLine returns the systemctl command line managing the server units of the user running the tests.
+func systemctlLine(args ...string) string {
+	return "systemctl " + strings.Join(systemctlArgs(args...), " ")
+}
+
+// assertCommands checks the commands run match the expected ones in order.
+// A '*' in an expected command matches any text, like the temporary folders.
+func assertCommands(t *testing.T, runner *utils.FakeRunner, expected []string) {
+	t.Helper()
+	if len(runner.Commands) != len(expected) {
+		t.Fatalf("expected %d commands, got %d:\n  %s", len(expected), len(runner.Commands),
+			strings.Join(runner.Commands, "\n  "))
+	}
+	for i, command := range runner.Commands {
+		if !matchCommand(expected[i], command) {
+			t.Errorf("command %d: expected %q, got %q", i, expected[i], command)
+		}
+	}
+}
+
+func matchCommand(pattern string, command string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == command
+	}
+	first, last := parts[0], parts[len(parts)-1]
+	if len(command) < len(first)+len(last) || !strings.HasPrefix(command, first) || !strings.HasSuffix(command, last) {
+		return false
+	}
+	middle := command[len(first) : len(command)-len(last)]
+	for _, part := range parts[1 : len(parts)-1] {
+		index := strings.Index(middle, part)
+		if index < 0 {
+			return false
+		}
+		middle = middle[index+len(part):]
+	}
+	return true
+}
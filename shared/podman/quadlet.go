@@ -0,0 +1,165 @@
+package podman
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// quadletMinVersion is the first podman version supporting Quadlet.
+const quadletMinVersion = "4.4"
+
+var quadletImageRegexp = regexp.MustCompile(`(?m)^Image=(.*)$`)
+
+// GetQuadletPath returns the path of the Quadlet container file of an instance.
+// The generated systemd service has the same name as the file.
+func GetQuadletPath(instance string) string {
+	return filepath.Join(getQuadletDir(), GetServiceName(instance)+".container")
+}
+
+func getVolumeQuadletPath(volume string) string {
+	return filepath.Join(getQuadletDir(), volume+".volume")
+}
+
+// getAllVolumeNames returns the names of all the volumes of an instance, including the cgroup one.
+func getAllVolumeNames(instance string) []string {
+	volumes := []string{GetVolumeName(instance, "cgroup")}
+	for volume := range GetVolumes(instance) {
+		volumes = append(volumes, volume)
+	}
+	sort.Strings(volumes)
+	return volumes
+}
+
+// isQuadletInstalled returns whether the instance is defined using Quadlet files.
+func isQuadletInstalled(instance string) bool {
+	_, err := os.Stat(GetQuadletPath(instance))
+	return err == nil
+}
+
+// isQuadletSupported returns whether the podman version on the host supports Quadlet.
+func isQuadletSupported() bool {
+	out, err := utils.GetQueryRunner().Output(exec.Command("podman", "version", "--format", "{{.Client.Version}}"))
+	if err != nil {
+		log.Printf("Failed to get podman version, using a legacy systemd service: %s\n", err)
+		return false
+	}
+	// Ignore the suffixes like in 4.9.4-rhel
+	version := strings.SplitN(strings.TrimSpace(string(out)), "-", 2)[0]
+	return utils.CompareVersions(version, quadletMinVersion) >= 0
+}
+
+const containerQuadletTemplate = `# {{ .Name }}.container, generated by uyuniadm
+# Use a {{ .Name }}.container.d/local.conf file to override
+
+[Unit]
+Description=Uyuni server image container service{{ if .Instance }} for {{ .Instance }} instance{{ end }}
+Wants=network.target
+After=network-online.target
+
+[Container]
+ContainerName={{ .ContainerName }}
+Image={{ .Image }}
+Environment=TZ={{ .Timezone }}
+AddCapability=NET_RAW
+Tmpfs=/run
+Volume={{ .CgroupVolume }}.volume:/sys/fs/cgroup:rw
+{{- range .Ports }}
+PublishPort={{ . }}
+{{- end }}
+{{- range $name, $path := .Volumes }}
+Volume={{ $name }}.volume:{{ $path }}
+{{- end }}
+HealthCmd=systemctl is-active -q multi-user.target
+HealthStartPeriod=15m
+{{- if .Args }}
+PodmanArgs={{ .Args }}
+{{- end }}
+
+[Service]
+Restart=on-failure
+TimeoutStopSec=180
+TimeoutStartSec=900
+
+[Install]
+WantedBy=multi-user.target default.target
+`
+
+const volumeQuadletTemplate = `# {{ .Name }}.volume, generated by uyuniadm
+
+[Volume]
+VolumeName={{ .Name }}
+`
+
+// generateQuadlet writes the Quadlet files of the server container and its volumes.
+// The volumes keep the same names as with the legacy service.
+func generateQuadlet(instance string, tz string, image string, podmanArgs []string, ports []string) error {
+	quadletDir := getQuadletDir()
+	if err := os.MkdirAll(quadletDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s folder: %w", quadletDir, err)
+	}
+
+	volumeTemplate := template.Must(template.New("volume").Parse(volumeQuadletTemplate))
+	for _, volume := range getAllVolumeNames(instance) {
+		path := getVolumeQuadletPath(volume)
+		if err := utils.WriteTemplate(volumeTemplate, path, 0644, struct{ Name string }{Name: volume}); err != nil {
+			return fmt.Errorf("failed to generate %s file: %w", path, err)
+		}
+	}
+
+	model := struct {
+		Name          string
+		Instance      string
+		ContainerName string
+		Image         string
+		Timezone      string
+		CgroupVolume  string
+		Ports         []string
+		Volumes       map[string]string
+		Args          string
+	}{
+		Name:          GetServiceName(instance),
+		Instance:      instance,
+		ContainerName: GetContainerName(instance),
+		Image:         image,
+		Timezone:      tz,
+		CgroupVolume:  GetVolumeName(instance, "cgroup"),
+		Ports:         ports,
+		Volumes:       GetVolumes(instance),
+		Args:          strings.Join(podmanArgs, " "),
+	}
+	containerTemplate := template.Must(template.New("container").Parse(containerQuadletTemplate))
+	path := GetQuadletPath(instance)
+	if err := utils.WriteTemplate(containerTemplate, path, 0644, model); err != nil {
+		return fmt.Errorf("failed to generate %s file: %w", path, err)
+	}
+	return nil
+}
+
+// removeQuadlet removes the Quadlet files of an instance.
+// The volumes are not removed, only their definition.
+func removeQuadlet(instance string, dryRun bool, verbose bool) {
+	paths := []string{GetQuadletPath(instance)}
+	for _, volume := range getAllVolumeNames(instance) {
+		paths = append(paths, getVolumeQuadletPath(volume))
+	}
+
+	for _, path := range paths {
+		if dryRun {
+			log.Printf("Would remove %s\n", path)
+			continue
+		}
+		if verbose {
+			log.Printf("Remove %s\n", path)
+		}
+		os.Remove(path)
+	}
+}
@@ -0,0 +1,125 @@
+package podman
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// upgradeTransaction records the state of the server before an upgrade to be able to restore it.
+type upgradeTransaction struct {
+	previousImage string
+	snapshotDir   string
+	instance      string
+	serviceName   string
+	globalFlags   *types.GlobalFlags
+	verbose       bool
+}
+
+// beginUpgrade stops the server and snapshots its volumes in a subfolder of snapshotsDir.
+func beginUpgrade(snapshotsDir string, globalFlags *types.GlobalFlags) (*upgradeTransaction, error) {
+	verbose := globalFlags.Verbose
+	previousImage, err := GetServiceImage(globalFlags.Instance)
+	if err != nil {
+		return nil, err
+	}
+	transaction := upgradeTransaction{
+		previousImage: previousImage,
+		instance:      globalFlags.Instance,
+		serviceName:   GetServiceName(globalFlags.Instance),
+		globalFlags:   globalFlags,
+		verbose:       verbose,
+	}
+	log.Printf("Previous image: %s\n", transaction.previousImage)
+
+	if err := os.MkdirAll(snapshotsDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create %s folder: %w", snapshotsDir, err)
+	}
+	snapshotDir, err := os.MkdirTemp(snapshotsDir, "upgrade-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot folder in %s: %w", snapshotsDir, err)
+	}
+	transaction.snapshotDir = snapshotDir
+
+	// The server needs to be stopped for the snapshots to be consistent
+	if err := utils.RunCmd("systemctl", systemctlArgs("stop", transaction.serviceName),
+		fmt.Sprintf("failed to stop %s service", transaction.serviceName)); err != nil {
+		os.RemoveAll(snapshotDir)
+		return nil, err
+	}
+
+	log.Printf("Saving the volumes in %s\n", snapshotDir)
+	for volume := range GetVolumes(transaction.instance) {
+		if err := utils.RunCmd("podman", []string{"volume", "export", "-o", transaction.getSnapshotPath(volume), volume},
+			fmt.Sprintf("failed to export volume %s", volume)); err != nil {
+			return nil, transaction.abort(err)
+		}
+	}
+	return &transaction, nil
+}
+
+// abort removes the snapshots and restarts the unchanged server when the snapshots couldn't all be taken.
+func (t *upgradeTransaction) abort(cause error) error {
+	t.commit()
+	if err := utils.RunCmd("systemctl", systemctlArgs("start", t.serviceName),
+		fmt.Sprintf("failed to restart %s service", t.serviceName)); err != nil {
+		return fmt.Errorf("%w\n%s", cause, err)
+	}
+	return cause
+}
+
+func (t *upgradeTransaction) getSnapshotPath(volume string) string {
+	return filepath.Join(t.snapshotDir, volume+".tar")
+}
+
+// commit removes the snapshots once the upgraded server is healthy.
+func (t *upgradeTransaction) commit() {
+	if err := os.RemoveAll(t.snapshotDir); err != nil {
+		log.Printf("Failed to remove the volumes snapshots in %s: %s\n", t.snapshotDir, err)
+	}
+}
+
+// rollback restores the volumes and the previous image.
+// The returned error explains why the upgrade failed and, if it happened, why the restoration failed.
+// The snapshots are kept if restoring them fails.
+func (t *upgradeTransaction) rollback(cause error) error {
+	log.Printf("%s, rolling back to %s\n", cause, t.previousImage)
+
+	if out, err := utils.GetRunner().CombinedOutput(exec.Command("systemctl", systemctlArgs("stop", t.serviceName)...)); err != nil {
+		log.Printf("Failed to stop %s service: %s\n", t.serviceName, strings.TrimSpace(string(out)))
+	}
+
+	for volume := range GetVolumes(t.instance) {
+		commands := [][]string{
+			{"volume", "rm", "-f", volume},
+			{"volume", "create", volume},
+			{"volume", "import", volume, t.getSnapshotPath(volume)},
+		}
+		for _, args := range commands {
+			if out, err := utils.GetRunner().CombinedOutput(exec.Command("podman", args...)); err != nil {
+				return fmt.Errorf("%w\nfailed to restore volume %s, snapshots are kept in %s:\n  %s",
+					cause, volume, t.snapshotDir, strings.ReplaceAll(string(out), "\n", "\n  "))
+			}
+		}
+	}
+
+	if err := UpdateSystemdServiceImage(t.instance, t.previousImage); err != nil {
+		return fmt.Errorf("%w\nfailed to restore the previous image: %s", cause, err)
+	}
+	if err := utils.RunCmd("systemctl", systemctlArgs("start", t.serviceName),
+		fmt.Sprintf("failed to start %s service", t.serviceName)); err != nil {
+		return fmt.Errorf("%w\nfailed to restart the previous server: %s", cause, err)
+	}
+	if err := NewBackend(t.globalFlags).WaitReady(); err != nil {
+		return fmt.Errorf("%w\nfailed to restart the previous server: %s", cause, err)
+	}
+	t.commit()
+
+	return fmt.Errorf("upgrade failed, server restored to %s: %w", t.previousImage, cause)
+}
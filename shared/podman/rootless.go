@@ -0,0 +1,149 @@
+package podman
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+)
+
+// A rootless server runs in the podman of the user running the tools: its units are systemd user units
+// managed with systemctl --user and its volumes belong to that user.
+
+// unprivilegedPortFile holds the lowest port non root users can bind.
+const unprivilegedPortFile = "/proc/sys/net/ipv4/ip_unprivileged_port_start"
+
+// Folders of the systemd units of a rootful server.
+var (
+	systemServicesDir = "/usr/lib/systemd/system"
+	systemQuadletDir  = "/etc/containers/systemd"
+)
+
+// IsRootless returns whether the server is managed with rootless podman, that is the tools don't run as root.
+func IsRootless() bool {
+	return os.Geteuid() != 0
+}
+
+// checkRootless returns an error if the rootless flag doesn't match the user running the tools.
+func checkRootless(rootless bool) error {
+	if rootless && !IsRootless() {
+		return errors.New("rootless mode requested: run as the user owning the server instead of root")
+	}
+	if !rootless && IsRootless() {
+		return errors.New("not running as root: use --rootless to manage a rootless server")
+	}
+	return nil
+}
+
+func getConfigHome() string {
+	if configHome := os.Getenv("XDG_CONFIG_HOME"); configHome != "" {
+		return configHome
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		// HOME may not be set when running from a service
+		current, userErr := user.Current()
+		if userErr != nil {
+			log.Printf("Failed to get the home folder, using the current one: %s\n", err)
+			return ".config"
+		}
+		home = current.HomeDir
+	}
+	return filepath.Join(home, ".config")
+}
+
+// getServicesDir returns the folder where the systemd service units are generated.
+func getServicesDir() string {
+	if IsRootless() {
+		return filepath.Join(getConfigHome(), "systemd", "user")
+	}
+	return systemServicesDir
+}
+
+// getQuadletDir returns the folder where podman's systemd generator looks for Quadlet files.
+func getQuadletDir() string {
+	if IsRootless() {
+		return filepath.Join(getConfigHome(), "containers", "systemd")
+	}
+	return systemQuadletDir
+}
+
+// systemctlArgs returns the systemctl arguments to manage the server units: user units for a rootless server.
+func systemctlArgs(args ...string) []string {
+	if IsRootless() {
+		return append([]string{"--user"}, args...)
+	}
+	return args
+}
+
+func getUnprivilegedPortStart() int {
+	content, err := os.ReadFile(unprivilegedPortFile)
+	if err != nil {
+		return 1024
+	}
+	start, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		return 1024
+	}
+	return start
+}
+
+// checkRootlessPorts verifies a rootless server can bind the host ports.
+// The ports are host:container values as returned by GetExposedPorts.
+func checkRootlessPorts(ports []string) types.CheckResult {
+	result := types.CheckResult{Name: "unprivileged ports", Status: types.CheckPass, Message: "all ports can be bound"}
+	if !IsRootless() {
+		result.Message = "not needed for rootful podman"
+		return result
+	}
+
+	start := getUnprivilegedPortStart()
+	lowest := start
+	low := []string{}
+	for _, port := range ports {
+		hostPort, err := strconv.Atoi(strings.Split(port, ":")[0])
+		if err != nil || hostPort >= start {
+			continue
+		}
+		low = append(low, strconv.Itoa(hostPort))
+		if hostPort < lowest {
+			lowest = hostPort
+		}
+	}
+
+	if len(low) > 0 {
+		result.Status = types.CheckFail
+		result.Message = fmt.Sprintf("ports %s are below %d: run 'sysctl net.ipv4.ip_unprivileged_port_start=%d' as root "+
+			"or remap them with --podman-port, for instance --podman-port 8443:443",
+			strings.Join(low, ", "), start, lowest)
+	}
+	return result
+}
+
+// checkLinger warns if the user services of a rootless server will be stopped when the user logs out.
+func checkLinger() types.CheckResult {
+	result := types.CheckResult{Name: "linger", Status: types.CheckPass, Message: "enabled"}
+	if !IsRootless() {
+		result.Message = "not needed for rootful podman"
+		return result
+	}
+
+	current, err := user.Current()
+	if err != nil {
+		result.Status = types.CheckWarn
+		result.Message = fmt.Sprintf("failed to get the current user: %s", err)
+		return result
+	}
+	if _, err := os.Stat(filepath.Join("/var/lib/systemd/linger", current.Username)); err != nil {
+		result.Status = types.CheckWarn
+		result.Message = "disabled, the server will stop when the user logs out: run 'loginctl enable-linger " +
+			current.Username + "'"
+	}
+	return result
+}
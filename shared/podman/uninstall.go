@@ -0,0 +1,84 @@
+package podman
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+func uninstallForPodman(globalFlags *types.GlobalFlags, dryRun bool, purge bool) error {
+	instance := globalFlags.Instance
+	serviceName := GetServiceName(instance)
+	servicePath := GetServicePath(instance)
+	containerName := GetContainerName(instance)
+
+	// Check if there is an uyuni-server service
+	if err := utils.GetQueryRunner().Run(exec.Command("systemctl", systemctlArgs("list-unit-files", serviceName+".service")...)); err != nil {
+		return fmt.Errorf("systemd has no %s.service unit, nothing to uninstall", serviceName)
+	}
+
+	// Force stop the pod
+	if out, _ := utils.GetQueryRunner().Output(exec.Command("podman", "ps", "-q", "-f", "name=^"+containerName+"$")); len(out) > 0 {
+		if dryRun {
+			log.Printf("Would run podman kill %s\n", containerName)
+		} else {
+			if err := utils.RunCmd("podman", []string{"kill", containerName}, "failed to kill the server"); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Disable the service. Quadlet generated services are only stopped as they are removed with their files.
+	quadlet := isQuadletInstalled(instance)
+	args := []string{"disable", "--now", serviceName}
+	if quadlet {
+		args = []string{"stop", serviceName}
+	}
+	args = systemctlArgs(args...)
+	if dryRun {
+		log.Printf("Would run systemctl %s\n", strings.Join(args, " "))
+	} else {
+		if err := utils.RunCmd("systemctl", args, "failed to disable server"); err != nil {
+			return err
+		}
+	}
+
+	// Remove the volumes
+	if purge {
+		for _, volume := range getAllVolumeNames(instance) {
+			if dryRun {
+				log.Printf("Would run podman volume rm %s\n", volume)
+			} else {
+				errorMessage := fmt.Sprintf("failed to remove volume %s", volume)
+				if err := utils.RunCmd("podman", []string{"volume", "rm", volume}, errorMessage); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	// Remove the service unit
+	if quadlet {
+		removeQuadlet(instance, dryRun, globalFlags.Verbose)
+	} else if dryRun {
+		log.Printf("Woud remove %s\n", servicePath)
+	} else {
+		if globalFlags.Verbose {
+			log.Printf("Remove %s\n", servicePath)
+		}
+		os.Remove(servicePath)
+	}
+
+	// Reload systemd daemon
+	if dryRun {
+		log.Printf("Would run systemctl %s\n", strings.Join(systemctlArgs("daemon-reload"), " "))
+	} else {
+		return utils.RunCmd("systemctl", systemctlArgs("daemon-reload"), "failed to reload systemd daemon")
+	}
+	return nil
+}
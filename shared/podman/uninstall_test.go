@@ -0,0 +1,153 @@
+package podman
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+func TestUninstallForPodman(t *testing.T) {
+	running := utils.FakeResult{Prefix: "podman ps -q", Output: "0123456789ab\n"}
+	tests := []struct {
+		name        string
+		quadlet     bool
+		dryRun      bool
+		purge       bool
+		results     []utils.FakeResult
+		expected    []string
+		expectedErr bool
+		keptUnit    bool
+	}{
+		{
+			name:    "running legacy service",
+			results: []utils.FakeResult{running},
+			expected: []string{
+				systemctlLine("list-unit-files", "uyuni-server.service"),
+				"podman ps -q -f name=^uyuni-server$",
+				"podman kill uyuni-server",
+				systemctlLine("disable", "--now", "uyuni-server"),
+				systemctlLine("daemon-reload"),
+			},
+		},
+		{
+			name:    "stopped quadlet with volumes",
+			quadlet: true,
+			purge:   true,
+			expected: append(append([]string{
+				systemctlLine("list-unit-files", "uyuni-server.service"),
+				"podman ps -q -f name=^uyuni-server$",
+				systemctlLine("stop", "uyuni-server"),
+			}, volumeRemovals()...),
+				systemctlLine("daemon-reload"),
+			),
+		},
+		{
+			name:    "dry run",
+			quadlet: true,
+			dryRun:  true,
+			purge:   true,
+			results: []utils.FakeResult{running},
+			expected: []string{
+				systemctlLine("list-unit-files", "uyuni-server.service"),
+				"podman ps -q -f name=^uyuni-server$",
+			},
+			keptUnit: true,
+		},
+		{
+			name:        "not installed",
+			results:     []utils.FakeResult{{Prefix: "systemctl", ExitCode: 1}},
+			expected:    []string{systemctlLine("list-unit-files", "uyuni-server.service")},
+			expectedErr: true,
+			keptUnit:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			runner := setUpTestHost(t, test.results)
+
+			unitPath := GetServicePath("")
+			if test.quadlet {
+				unitPath = GetQuadletPath("")
+			}
+			if err := os.MkdirAll(filepath.Dir(unitPath), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(unitPath, []byte("[Unit]\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			err := uninstallForPodman(&types.GlobalFlags{}, test.dryRun, test.purge)
+			if test.expectedErr && err == nil {
+				t.Error("expected an error")
+			} else if !test.expectedErr && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+			assertCommands(t, runner, test.expected)
+
+			if _, err := os.Stat(unitPath); (err == nil) != test.keptUnit {
+				t.Errorf("unit file kept: %t, expected %t", err == nil, test.keptUnit)
+			}
+		})
+	}
+}
+
+// volumeRemovals returns the commands removing all the volumes of the default instance.
+func volumeRemovals() []string {
+	commands := []string{}
+	for _, volume := range getAllVolumeNames("") {
+		commands = append(commands, "podman volume rm "+volume)
+	}
+	return commands
+}
+
+func TestUninstallForPodmanDryRun(t *testing.T) {
+	tests := []struct {
+		name        string
+		results     []utils.FakeResult
+		printed     []string
+		expectedErr bool
+	}{
+		{
+			name:    "running server",
+			results: []utils.FakeResult{{Prefix: "podman ps -q", Output: "0123456789ab\n"}},
+			printed: []string{
+				"Would run podman kill uyuni-server",
+				"Would run systemctl " + strings.Join(systemctlArgs("disable", "--now", "uyuni-server"), " "),
+			},
+		},
+		{
+			name:        "not installed",
+			results:     []utils.FakeResult{{Prefix: "systemctl", ExitCode: 1}},
+			expectedErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			runner, out := setUpDryRunTestHost(t, test.results)
+
+			err := uninstallForPodman(&types.GlobalFlags{}, true, false)
+			if test.expectedErr && err == nil {
+				t.Error("expected an error")
+			} else if !test.expectedErr && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+			assertPrinted(t, out.String(), test.printed)
+			if strings.Contains(out.String(), "> Would run") {
+				t.Errorf("no command should go through the dry run runner:\n%s", out.String())
+			}
+
+			// Only the queries are run
+			for _, command := range runner.Commands {
+				if !strings.HasPrefix(command, "podman ps") && !strings.Contains(command, "list-unit-files") {
+					t.Errorf("unexpected command run: %s", command)
+				}
+			}
+		})
+	}
+}
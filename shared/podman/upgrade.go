@@ -0,0 +1,75 @@
+package podman
+
+import (
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+func upgradePodman(viper *viper.Viper, globalFlags *types.GlobalFlags) error {
+	image := fmt.Sprintf("%s:%s", viper.GetString("image"), viper.GetString("tag"))
+
+	if err := pullImage(viper, globalFlags.Timeouts.WithDefaults().ImagePull); err != nil {
+		return err
+	}
+
+	// Check the new image is not older than the running one
+	backend := NewBackend(globalFlags)
+	currentRelease, err := utils.GetServerCmdOutput(backend, utils.ReleaseCommand)
+	if err != nil {
+		return err
+	}
+	newRelease, err := backend.GetImageRelease(viper, image)
+	if err != nil {
+		return err
+	}
+	if err := utils.CheckUpgradeVersions(currentRelease, newRelease); err != nil {
+		return err
+	}
+
+	snapshotsDir := viper.GetString("snapshot.dir")
+	if snapshotsDir == "" {
+		if snapshotsDir, err = utils.GetDefaultStateDir("snapshots", "--snapshot-dir"); err != nil {
+			return err
+		}
+	}
+	transaction, err := beginUpgrade(snapshotsDir, globalFlags)
+	if err != nil {
+		return err
+	}
+
+	if err := UpdateSystemdServiceImage(globalFlags.Instance, image); err != nil {
+		return transaction.rollback(err)
+	}
+
+	log.Println("Starting the server with the new image...")
+	serviceName := GetServiceName(globalFlags.Instance)
+	if err := utils.RunCmd("systemctl", systemctlArgs("start", serviceName),
+		fmt.Sprintf("failed to start %s service", serviceName)); err != nil {
+		return transaction.rollback(err)
+	}
+	timeout := time.Duration(viper.GetInt("rollback.timeout")) * time.Second
+	if err := utils.WaitForServer(NewBackend(globalFlags), timeout, true); err != nil {
+		return transaction.rollback(err)
+	}
+
+	log.Println("Upgrading the database schema")
+	cmd := exec.Command("podman", "exec", GetContainerName(globalFlags.Instance), "sh", "-c", utils.SchemaUpgradeCommand)
+	out, err := utils.GetRunner().CombinedOutput(cmd)
+	if globalFlags.Verbose {
+		log.Printf("Database schema upgrade output:\n  %s\n", strings.ReplaceAll(strings.TrimSpace(string(out)), "\n", "\n  "))
+	}
+	if err != nil {
+		return transaction.rollback(utils.NewCmdError("failed to upgrade the database schema", cmd, out, err))
+	}
+
+	transaction.commit()
+	log.Printf("Server upgraded to %s\n", image)
+	return nil
+}
@@ -0,0 +1,77 @@
+package podman
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// isVolumeEmpty returns true if the volume doesn't exist or contains no file.
+func isVolumeEmpty(name string) bool {
+	out, err := utils.GetQueryRunner().Output(exec.Command("podman", "volume", "inspect", "--format", "{{.Mountpoint}}", name))
+	if err != nil {
+		return true
+	}
+	entries, err := os.ReadDir(strings.TrimSpace(string(out)))
+	return err == nil && len(entries) == 0
+}
+
+// createVolumes creates the volumes of an instance.
+// Existing volumes are replaced if force is true: the installed server is then removed first
+// as the volumes can't be replaced while it uses them.
+func createVolumes(globalFlags *types.GlobalFlags, force bool) error {
+	instance := globalFlags.Instance
+	volumes := GetVolumes(instance)
+	nonEmpty := []string{}
+	for name := range volumes {
+		if !isVolumeEmpty(name) {
+			nonEmpty = append(nonEmpty, name)
+		}
+	}
+	if len(nonEmpty) > 0 && !force {
+		return fmt.Errorf("volumes with data found, use --force to replace them: %s", strings.Join(nonEmpty, ", "))
+	}
+
+	installed, err := isServiceInstalled(instance)
+	if err != nil {
+		return err
+	}
+	if installed {
+		if !force {
+			return fmt.Errorf("%s service already present, use --force to replace the server", GetServiceName(instance))
+		}
+		log.Printf("Removing the %s service to replace its volumes\n", GetServiceName(instance))
+		if err := uninstallForPodman(globalFlags, false, false); err != nil {
+			return err
+		}
+	}
+
+	for name := range volumes {
+		if err := utils.GetQueryRunner().Run(exec.Command("podman", "volume", "exists", name)); err == nil {
+			if err := utils.RunCmd("podman", []string{"volume", "rm", "-f", name},
+				fmt.Sprintf("failed to remove volume %s", name)); err != nil {
+				return err
+			}
+		}
+		if err := utils.RunCmd("podman", []string{"volume", "create", name},
+			fmt.Sprintf("failed to create volume %s", name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func importVolume(name string, content io.Reader) error {
+	cmd := exec.Command("podman", "volume", "import", name, "-")
+	cmd.Stdin = content
+	if out, err := utils.GetRunner().CombinedOutput(cmd); err != nil {
+		return utils.NewCmdError(fmt.Sprintf("failed to import volume %s", name), cmd, out, err)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package types
+
+import (
+	"io"
+	"os/exec"
+
+	"github.com/spf13/viper"
+)
+
+// Backend is implemented by each container runtime the tools can manage the server with.
+//
+// The commands only talk to the server through this interface, adding a new runtime
+// only requires a new implementation registered in the backend package.
+// The methods return the errors for the commands to report them: failed commands are
+// utils.CmdError values and a missing server container a utils.NotRunningError.
+type Backend interface {
+	// Name returns the identifier of the backend, like podman or kubernetes.
+	Name() string
+
+	// Probe returns an error explaining why the backend can't be used on this machine.
+	Probe() error
+
+	// Check runs the pre-flight checks for an installation with fqdn on this machine.
+	// An empty fqdn skips the DNS checks.
+	Check(viper *viper.Viper, fqdn string) []CheckResult
+
+	// Exec runs a command in the server container using `sh -c`.
+	// The env values without '=' are taken from the local environment.
+	Exec(globalFlags *GlobalFlags, interactive bool, tty bool, env []string, args ...string) error
+
+	// Command returns a command running args in the server container with standard input attached.
+	// This is useful to stream data to or from the container.
+	Command(args ...string) (*exec.Cmd, error)
+
+	// Copy transfers a file to or from the server container.
+	// Prefix one of src or dst parameters with `server:` to designate the path is in the container
+	// user and group parameters are used to set the owner of a file transfered in the container.
+	Copy(globalFlags *GlobalFlags, src string, dst string, user string, group string) error
+
+	// Logs prints the output of the server container.
+	Logs(globalFlags *GlobalFlags, options LogsOptions) error
+
+	// GetImage returns the image of the server container.
+	GetImage() (string, error)
+
+	// GetImageRelease returns the content of the server release file in an image, pulling it if needed.
+	GetImageRelease(viper *viper.Viper, image string) (string, error)
+
+	// Status prints the backend-specific details of the server container state.
+	Status(globalFlags *GlobalFlags) error
+
+	// GetContainerStatus returns the state of the server container.
+	// A container that doesn't exist is reported with a utils.NotRunningError.
+	GetContainerStatus() (*ContainerStatus, error)
+
+	// Install deploys the server container and waits for it to be started.
+	// It returns the backend-specific environment variables to pass to the setup script.
+	Install(viper *viper.Viper, globalFlags *GlobalFlags, fqdn string) (map[string]string, error)
+
+	// CreateVolumes creates empty volumes for the server.
+	// Volumes with data are only replaced if force is true.
+	CreateVolumes(viper *viper.Viper, globalFlags *GlobalFlags, force bool) error
+
+	// ImportVolume extracts a tar archive into a server volume.
+	ImportVolume(viper *viper.Viper, globalFlags *GlobalFlags, name string, content io.Reader) error
+
+	// Deploy starts the server container on the existing volumes and waits for it to be started.
+	// The failed server services are not reported as the database may not be restored yet.
+	Deploy(viper *viper.Viper, globalFlags *GlobalFlags, fqdn string) error
+
+	// Migrate copies the data of the source server into the volumes.
+	// If the final configuration is true, the source server is stopped and the server container started.
+	// Otherwise only a pre-synchronization of the data is done.
+	Migrate(viper *viper.Viper, globalFlags *GlobalFlags, sourceFqdn string) error
+
+	// Upgrade switches the server container to the image defined by the image and tag configuration
+	// and upgrades the database schema.
+	Upgrade(viper *viper.Viper, globalFlags *GlobalFlags) error
+
+	// RenewCertificates regenerates the server certificate, or imports it if the cert.server.cert file is configured.
+	// If rotateCa is true, the CA is regenerated too.
+	RenewCertificates(viper *viper.Viper, globalFlags *GlobalFlags, fqdn string, rotateCa bool) error
+
+	// GetCertificates returns the CA and server certificates in PEM format.
+	GetCertificates(viper *viper.Viper) (ca []byte, server []byte, err error)
+
+	// Uninstall removes the server container.
+	// If dryRun is true, only show what would be done.
+	Uninstall(globalFlags *GlobalFlags, dryRun bool, purge bool) error
+
+	// WaitReady waits for the multi-user systemd target to be reached in the container of a set up server.
+	// The failed server services are reported without waiting for the timeout.
+	WaitReady() error
+}
@@ -0,0 +1,27 @@
+package types
+
+// BackupManifestName is the name of the manifest file in the backup archives.
+const BackupManifestName = "manifest.json"
+
+// BackupManifest describes the content of a backup archive.
+type BackupManifest struct {
+	Image    string `json:"image"`
+	Version  string `json:"version"`
+	Fqdn     string `json:"fqdn"`
+	Timezone string `json:"timezone"`
+	Date     string `json:"date"`
+
+	// Database is the SQL dump of the PostgreSQL server.
+	Database BackupEntry `json:"database"`
+	// DatabaseConfig is a tar archive of the PostgreSQL configuration files.
+	DatabaseConfig BackupEntry `json:"databaseConfig"`
+	// Volumes maps the volume names to the tar archives of their content.
+	Volumes map[string]BackupEntry `json:"volumes"`
+}
+
+// BackupEntry describes a file of the backup archive.
+type BackupEntry struct {
+	File     string `json:"file"`
+	Path     string `json:"path,omitempty"`
+	Checksum string `json:"checksum"`
+}
@@ -1,6 +1,61 @@
 package types
 
+import "time"
+
 type GlobalFlags struct {
-	Verbose    bool
+	Verbose bool
+	// DryRun is set to only print the commands instead of running them.
+	DryRun     bool
 	ConfigPath string
+	Backend    string
+	Namespace  string
+	Instance   string
+	Rootless   bool
+	// KubeContext is the kubeconfig context to use, the current one if empty.
+	KubeContext string
+	// Timeouts are how long to wait for each phase of the server start.
+	Timeouts Timeouts
+}
+
+// Default timeouts of the phases waited for.
+const (
+	DefaultImagePullTimeout    = 15 * time.Minute
+	DefaultDeploymentTimeout   = 5 * time.Minute
+	DefaultServerStartTimeout  = 5 * time.Minute
+	DefaultCertificatesTimeout = 2 * time.Minute
+	DefaultMigrationTimeout    = 24 * time.Hour
+)
+
+// Timeouts are the maximum durations of the phases waited for.
+type Timeouts struct {
+	// ImagePull is the time to pull the server or a helm chart image.
+	ImagePull time.Duration
+	// Deployment is the time for a kubernetes pod to be created and to turn ready once its image is pulled.
+	Deployment time.Duration
+	// ServerStart is the time for the systemd services to start in the server container.
+	ServerStart time.Duration
+	// Certificates is the time for cert-manager to issue certificates and trust-manager to copy them.
+	Certificates time.Duration
+	// Migration is the time for the kubernetes migration job to synchronize the data of the source server.
+	Migration time.Duration
+}
+
+// WithDefaults returns the timeouts with the default values for the unset ones.
+func (t Timeouts) WithDefaults() Timeouts {
+	if t.ImagePull <= 0 {
+		t.ImagePull = DefaultImagePullTimeout
+	}
+	if t.Deployment <= 0 {
+		t.Deployment = DefaultDeploymentTimeout
+	}
+	if t.ServerStart <= 0 {
+		t.ServerStart = DefaultServerStartTimeout
+	}
+	if t.Certificates <= 0 {
+		t.Certificates = DefaultCertificatesTimeout
+	}
+	if t.Migration <= 0 {
+		t.Migration = DefaultMigrationTimeout
+	}
+	return t
 }
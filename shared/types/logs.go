@@ -0,0 +1,13 @@
+package types
+
+import "time"
+
+// LogsOptions selects the server log lines to show.
+type LogsOptions struct {
+	// Follow keeps showing the new lines until interrupted.
+	Follow bool
+	// Since is the time of the oldest line to show, all the lines if zero.
+	Since time.Time
+	// Tail is the number of lines to show from the end of the logs, all the lines if negative.
+	Tail int
+}
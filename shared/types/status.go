@@ -0,0 +1,53 @@
+package types
+
+import "time"
+
+// ContainerStatus is the state of the server container or pod as seen by the backend.
+type ContainerStatus struct {
+	// Name is the name of the container or pod.
+	Name string `json:"name"`
+	// State is the backend-specific state, like running, exited or CrashLoopBackOff.
+	State   string `json:"state"`
+	Running bool   `json:"running"`
+	Image   string `json:"image"`
+	// StartedAt is the time the server container was started, nil if it is not running.
+	StartedAt *time.Time `json:"startedAt,omitempty"`
+}
+
+// ServiceStatus is the state of a systemd service in the server container.
+type ServiceStatus struct {
+	Name string `json:"name"`
+	// State is the systemd active state of the service, like active or failed.
+	State string `json:"state"`
+}
+
+// VolumeStatus is the disk usage of a server volume.
+type VolumeStatus struct {
+	Name        string `json:"name"`
+	Path        string `json:"path"`
+	Size        uint64 `json:"size"`
+	Used        uint64 `json:"used"`
+	UsedPercent int    `json:"usedPercent"`
+}
+
+// CertificateStatus is the validity of a server certificate.
+type CertificateStatus struct {
+	// Name is the role of the certificate: ca or server.
+	Name     string    `json:"name"`
+	Subject  string    `json:"subject"`
+	NotAfter time.Time `json:"notAfter"`
+	DaysLeft int       `json:"daysLeft"`
+}
+
+// ServerStatus is the health overview of a server.
+type ServerStatus struct {
+	Backend      string              `json:"backend"`
+	Container    ContainerStatus     `json:"container"`
+	Tag          string              `json:"tag"`
+	Uptime       string              `json:"uptime,omitempty"`
+	Services     []ServiceStatus     `json:"services"`
+	Volumes      []VolumeStatus      `json:"volumes"`
+	Certificates []CertificateStatus `json:"certificates"`
+	// Problems lists why the server is degraded, empty if it is healthy.
+	Problems []string `json:"problems"`
+}
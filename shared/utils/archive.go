@@ -0,0 +1,184 @@
+package utils
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ArchiveWriter writes a tar archive compressed according to the file extension.
+type ArchiveWriter struct {
+	*tar.Writer
+	file    *os.File
+	gzip    *gzip.Writer
+	zstdCmd *exec.Cmd
+	zstdIn  io.WriteCloser
+}
+
+// CreateArchive creates a tar archive at path.
+// Paths ending with .zst are compressed using the zstd command, those ending with .gz using gzip.
+func CreateArchive(path string) (*ArchiveWriter, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s archive: %w", path, err)
+	}
+
+	archive := ArchiveWriter{file: file}
+	var out io.Writer = file
+	switch {
+	case strings.HasSuffix(path, ".zst"):
+		archive.zstdCmd = exec.Command("zstd", "-q", "-T0", "-c")
+		archive.zstdCmd.Stdout = file
+		archive.zstdCmd.Stderr = os.Stderr
+		if archive.zstdIn, err = archive.zstdCmd.StdinPipe(); err != nil {
+			file.Close()
+			return nil, err
+		}
+		if err = archive.zstdCmd.Start(); err != nil {
+			file.Close()
+			return nil, NewCmdError("failed to run zstd to compress the archive", archive.zstdCmd, nil, err)
+		}
+		out = archive.zstdIn
+	case strings.HasSuffix(path, ".gz"):
+		archive.gzip = gzip.NewWriter(file)
+		out = archive.gzip
+	}
+	archive.Writer = tar.NewWriter(out)
+	return &archive, nil
+}
+
+// AddFile adds the file at path to the archive as name.
+func (a *ArchiveWriter) AddFile(name string, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	header := tar.Header{Name: name, Mode: 0600, Size: info.Size(), ModTime: info.ModTime()}
+	if err = a.WriteHeader(&header); err != nil {
+		return fmt.Errorf("failed to add %s to the archive: %w", name, err)
+	}
+	if _, err = io.Copy(a, file); err != nil {
+		return fmt.Errorf("failed to add %s to the archive: %w", name, err)
+	}
+	return nil
+}
+
+// AddContent adds a file with name and content to the archive.
+func (a *ArchiveWriter) AddContent(name string, content []byte) error {
+	header := tar.Header{Name: name, Mode: 0600, Size: int64(len(content)), ModTime: time.Now()}
+	if err := a.WriteHeader(&header); err != nil {
+		return fmt.Errorf("failed to add %s to the archive: %w", name, err)
+	}
+	if _, err := a.Write(content); err != nil {
+		return fmt.Errorf("failed to add %s to the archive: %w", name, err)
+	}
+	return nil
+}
+
+// Close flushes the archive and the compression and closes the file.
+func (a *ArchiveWriter) Close() error {
+	if err := a.Writer.Close(); err != nil {
+		return err
+	}
+	if a.gzip != nil {
+		if err := a.gzip.Close(); err != nil {
+			return err
+		}
+	}
+	if a.zstdCmd != nil {
+		a.zstdIn.Close()
+		if err := a.zstdCmd.Wait(); err != nil {
+			return err
+		}
+	}
+	return a.file.Close()
+}
+
+// ArchiveReader reads a tar archive decompressed according to the file extension.
+type ArchiveReader struct {
+	*tar.Reader
+	file    *os.File
+	gzip    *gzip.Reader
+	zstdCmd *exec.Cmd
+}
+
+// OpenArchive opens a tar archive at path.
+// Paths ending with .zst are decompressed using the zstd command, those ending with .gz using gzip.
+func OpenArchive(path string) (*ArchiveReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s archive: %w", path, err)
+	}
+
+	archive := ArchiveReader{file: file}
+	var in io.Reader = file
+	switch {
+	case strings.HasSuffix(path, ".zst"):
+		archive.zstdCmd = exec.Command("zstd", "-q", "-d", "-c")
+		archive.zstdCmd.Stdin = file
+		archive.zstdCmd.Stderr = os.Stderr
+		out, err := archive.zstdCmd.StdoutPipe()
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		if err = archive.zstdCmd.Start(); err != nil {
+			file.Close()
+			return nil, NewCmdError("failed to run zstd to decompress the archive", archive.zstdCmd, nil, err)
+		}
+		in = out
+	case strings.HasSuffix(path, ".gz"):
+		if archive.gzip, err = gzip.NewReader(file); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to decompress %s archive: %w", path, err)
+		}
+		in = archive.gzip
+	}
+	archive.Reader = tar.NewReader(in)
+	return &archive, nil
+}
+
+// Close stops the decompression and closes the file.
+func (a *ArchiveReader) Close() error {
+	if a.zstdCmd != nil {
+		// zstd may fail writing to the closed pipe if the archive wasn't read until the end
+		a.zstdCmd.Process.Kill()
+		a.zstdCmd.Wait()
+	}
+	if a.gzip != nil {
+		a.gzip.Close()
+	}
+	return a.file.Close()
+}
+
+// SaveCommandOutput writes the standard output of cmd to a file at path.
+// The sha256 checksum of the output is returned.
+func SaveCommandOutput(cmd *exec.Cmd, path string) (string, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	var stderr strings.Builder
+	cmd.Stdout = io.MultiWriter(file, hash)
+	cmd.Stderr = &stderr
+
+	if err = GetRunner().Run(cmd); err != nil {
+		return "", NewCmdError("", cmd, []byte(stderr.String()), err)
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
@@ -0,0 +1,152 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+)
+
+// CheckBinary verifies a command is available in the PATH.
+// If required is false, a missing command only results in a warning.
+func CheckBinary(name string, required bool) types.CheckResult {
+	result := types.CheckResult{Name: "binary " + name, Status: types.CheckPass}
+	if path, err := exec.LookPath(name); err != nil {
+		result.Status = types.CheckWarn
+		if required {
+			result.Status = types.CheckFail
+		}
+		result.Message = "not found in PATH"
+	} else {
+		result.Message = path
+	}
+	return result
+}
+
+// CheckFqdn verifies the FQDN resolves to IP addresses and that those resolve back to the FQDN.
+func CheckFqdn(fqdn string) []types.CheckResult {
+	forward := types.CheckResult{Name: "DNS " + fqdn, Status: types.CheckPass}
+	addresses, err := net.LookupHost(fqdn)
+	if err != nil {
+		forward.Status = types.CheckFail
+		forward.Message = err.Error()
+		return []types.CheckResult{forward}
+	}
+	forward.Message = "resolves to " + strings.Join(addresses, ", ")
+
+	results := []types.CheckResult{forward}
+	for _, address := range addresses {
+		reverse := types.CheckResult{Name: "reverse DNS " + address, Status: types.CheckPass}
+		names, err := net.LookupAddr(address)
+		if err != nil {
+			reverse.Status = types.CheckWarn
+			reverse.Message = err.Error()
+		} else if !Contains(names, fqdn) && !Contains(names, fqdn+".") {
+			reverse.Status = types.CheckWarn
+			reverse.Message = fmt.Sprintf("resolves to %s instead of %s", strings.Join(names, ", "), fqdn)
+		} else {
+			reverse.Message = "resolves to " + fqdn
+		}
+		results = append(results, reverse)
+	}
+	return results
+}
+
+// CheckFreeSpace verifies the filesystem containing path has at least size GiB available.
+func CheckFreeSpace(name string, path string, size int) types.CheckResult {
+	result := types.CheckResult{Name: "free space " + name, Status: types.CheckPass}
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		result.Status = types.CheckWarn
+		result.Message = fmt.Sprintf("failed to get free space of %s: %s", path, err)
+		return result
+	}
+	free := stat.Bavail * uint64(stat.Bsize) / (1024 * 1024 * 1024)
+	result.Message = fmt.Sprintf("%d GiB available in %s, %d GiB needed", free, path, size)
+	if free < uint64(size) {
+		result.Status = types.CheckFail
+	}
+	return result
+}
+
+// CheckPort verifies a port is not already used on the machine.
+func CheckPort(protocol string, port string) types.CheckResult {
+	result := types.CheckResult{Name: fmt.Sprintf("port %s/%s", port, protocol), Status: types.CheckPass, Message: "available"}
+	var err error
+	if protocol == "udp" {
+		var conn net.PacketConn
+		if conn, err = net.ListenPacket(protocol, ":"+port); err == nil {
+			conn.Close()
+		}
+	} else {
+		var listener net.Listener
+		if listener, err = net.Listen(protocol, ":"+port); err == nil {
+			listener.Close()
+		}
+	}
+
+	if errors.Is(err, syscall.EADDRINUSE) {
+		result.Status = types.CheckFail
+		result.Message = "already in use"
+	} else if err != nil {
+		result.Status = types.CheckWarn
+		result.Message = err.Error()
+	}
+	return result
+}
+
+// HasFailedChecks returns true if one of the results is a failure.
+func HasFailedChecks(results []types.CheckResult) bool {
+	for _, result := range results {
+		if result.Status == types.CheckFail {
+			return true
+		}
+	}
+	return false
+}
+
+// PrintCheckResults writes the results to out in format: table or json.
+func PrintCheckResults(results []types.CheckResult, format string, out io.Writer) error {
+	switch format {
+	case "json":
+		content, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to convert the check results to JSON: %w", err)
+		}
+		_, err = fmt.Fprintln(out, string(content))
+		return err
+	case "table":
+	default:
+		return &ConfigError{Message: fmt.Sprintf("invalid output format %s, possible values are: table, json", format)}
+	}
+
+	writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "STATUS\tCHECK\tDETAILS")
+	for _, result := range results {
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", strings.ToUpper(string(result.Status)), result.Name, result.Message)
+	}
+	return writer.Flush()
+}
+
+// RunPreflightChecks prints the failed and warning check results and returns an error if one of them failed.
+func RunPreflightChecks(results []types.CheckResult) error {
+	if HasFailedChecks(results) {
+		PrintCheckResults(results, "table", os.Stdout)
+		return errors.New("pre-flight checks failed, fix the problems or use --skip-checks to ignore them")
+	}
+	for _, result := range results {
+		if result.Status == types.CheckWarn {
+			log.Printf("Warning: %s: %s\n", result.Name, result.Message)
+		}
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"path"
@@ -14,7 +15,10 @@ import (
 const envPrefix = "UYUNI"
 const appName = "uyuni-tools"
 
-func ReadConfig(configPath string, configFilename string, cmd *cobra.Command) *viper.Viper {
+// ReadConfig returns the configuration merging the command flags, the UYUNI_* environment variables
+// and the configFilename configuration file or the one at configPath.
+// A ConfigError is returned if the configuration file can't be parsed.
+func ReadConfig(configPath string, configFilename string, cmd *cobra.Command) (*viper.Viper, error) {
 	v := viper.New()
 
 	v.SetConfigType("yaml")
@@ -32,13 +36,15 @@ func ReadConfig(configPath string, configFilename string, cmd *cobra.Command) *v
 		v.AddConfigPath(".")
 	}
 
-	bindFlags(cmd, v)
+	if err := bindFlags(cmd, v); err != nil {
+		return nil, err
+	}
 
 	if err := v.ReadInConfig(); err != nil {
 		// It's okay if there isn't a config file
 		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
 			// TODO Provide help on the config file format
-			log.Fatalf("Failed to parse configuration file %s: %s", v.ConfigFileUsed(), err)
+			return nil, &ConfigError{Message: "failed to parse configuration file " + v.ConfigFileUsed(), Err: err}
 		}
 	}
 
@@ -48,15 +54,20 @@ func ReadConfig(configPath string, configFilename string, cmd *cobra.Command) *v
 
 	v.AutomaticEnv()
 
-	return v
+	return v, nil
 }
 
 // Bind each cobra flag to its associated viper configuration (config file and environment variable)
-func bindFlags(cmd *cobra.Command, v *viper.Viper) {
+func bindFlags(cmd *cobra.Command, v *viper.Viper) error {
+	var bindErr error
 	cmd.Flags().VisitAll(func(f *pflag.Flag) {
 		configName := strings.ReplaceAll(f.Name, "-", ".")
-		if err := v.BindPFlag(configName, f); err != nil {
-			log.Fatalf("Failed to bind %s config to parameter %s: %s\n", configName, f.Name, err)
+		if err := v.BindPFlag(configName, f); err != nil && bindErr == nil {
+			bindErr = &ConfigError{
+				Message: fmt.Sprintf("failed to bind %s config to parameter %s", configName, f.Name),
+				Err:     err,
+			}
 		}
 	})
+	return bindErr
 }
@@ -1,42 +1,23 @@
 package utils
 
-import (
-	"log"
-	"strings"
+import "strings"
 
-	"github.com/uyuni-project/uyuni-tools/shared/types"
-)
-
-// Copy transfers a file to or from the container.
-// Prefix one of src or dst parameters with `server:` to designate the path is in the container
-// user and group parameters are used to set the owner of a file transfered in the container.
-func Copy(globalFlags *types.GlobalFlags, src string, dst string, user string, group string) {
-	command, podName := GetPodName(true)
-	var commandArgs []string
-	extraArgs := []string{}
+// GetCopyPaths replaces the `server:` prefix of the src and dst paths with the pod name.
+func GetCopyPaths(podName string, src string, dst string) (string, string) {
 	srcExpanded := strings.Replace(src, "server:", podName+":", 1)
 	dstExpanded := strings.Replace(dst, "server:", podName+":", 1)
+	return srcExpanded, dstExpanded
+}
 
-	switch command {
-	case "podman":
-		commandArgs = []string{"cp", srcExpanded, dstExpanded}
-	case "kubectl":
-		commandArgs = []string{"cp", "-c", "uyuni", srcExpanded, dstExpanded}
-		extraArgs = []string{"-c", "uyuni", "--"}
-	default:
-		log.Fatalf("Unknown container kind: %s\n", command)
+// GetChownArgs returns the command to run in the container to change the owner of a copied file.
+// An empty slice is returned if the destination is not in the container or no user is defined.
+func GetChownArgs(dst string, user string, group string) []string {
+	if user == "" || !strings.HasPrefix(dst, "server:") {
+		return []string{}
 	}
-
-	RunCmd(command, commandArgs, "Failed to copy file", globalFlags.Verbose)
-
-	if user != "" && strings.HasPrefix(dst, "server:") {
-		execArgs := []string{"exec", podName}
-		execArgs = append(execArgs, extraArgs...)
-		owner := user
-		if group != "" {
-			owner = user + ":" + group
-		}
-		execArgs = append(execArgs, "chown", owner, strings.Replace(dst, "server:", "", 1))
-		RunCmd(command, execArgs, "Failed to change file owner", globalFlags.Verbose)
+	owner := user
+	if group != "" {
+		owner = user + ":" + group
 	}
+	return []string{"chown", owner, strings.Replace(dst, "server:", "", 1)}
 }
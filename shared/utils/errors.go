@@ -0,0 +1,157 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Exit codes of the tools for the errors returned by the commands.
+// A failed command run interactively, like uyunictl exec, exits with the code of that command.
+const (
+	ExitFailure       = 1
+	ExitConfigInvalid = 2
+	ExitNotRunning    = 3
+	ExitDegraded      = 4
+)
+
+// CmdError is returned when a command fails to start or exits with a non zero code.
+type CmdError struct {
+	// Message describes what failed from the caller's point of view.
+	Message string
+	Command string
+	Args    []string
+	// ExitCode is -1 if the command couldn't be started.
+	ExitCode int
+	// Output is the captured output of the command, empty if it was attached to the terminal.
+	Output string
+	// Interactive is true if the command output was already shown to the user.
+	Interactive bool
+	Err         error
+}
+
+// NewCmdError returns a CmdError for the command run by cmd.
+func NewCmdError(message string, cmd *exec.Cmd, output []byte, err error) *CmdError {
+	exitCode := CmdExitCode(err)
+	args := []string{}
+	if len(cmd.Args) > 1 {
+		args = cmd.Args[1:]
+	}
+	return &CmdError{
+		Message:  message,
+		Command:  cmd.Path,
+		Args:     args,
+		ExitCode: exitCode,
+		Output:   string(output),
+		Err:      err,
+	}
+}
+
+func (e *CmdError) Error() string {
+	message := e.Message
+	if message == "" {
+		message = fmt.Sprintf("failed to run %s", strings.Join(append([]string{e.Command}, e.Args...), " "))
+	}
+	if output := strings.TrimSpace(e.Output); output != "" {
+		return fmt.Sprintf("%s:\n  %s", message, strings.ReplaceAll(output, "\n", "\n  "))
+	}
+	return fmt.Sprintf("%s: %s", message, e.Err)
+}
+
+func (e *CmdError) Unwrap() error {
+	return e.Err
+}
+
+// NotRunningError is returned when the server container or pod can't be found.
+type NotRunningError struct {
+	// Name is the container name or the namespace of the server pod.
+	Name    string
+	Backend string
+	Err     error
+}
+
+func (e *NotRunningError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("server %s is not running on %s: %s", e.Name, e.Backend, e.Err)
+	}
+	return fmt.Sprintf("server %s is not running on %s", e.Name, e.Backend)
+}
+
+func (e *NotRunningError) Unwrap() error {
+	return e.Err
+}
+
+// ConfigError is returned when the configuration file or the parameters are invalid.
+type ConfigError struct {
+	Message string
+	Err     error
+}
+
+func (e *ConfigError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// TimeoutError is returned when something waited for didn't happen in time.
+type TimeoutError struct {
+	// Description is what was waited for, like "the uyuni pod to be ready".
+	Description string
+	Timeout     time.Duration
+	// Err is the reason of the last unsuccessful check, if any.
+	Err error
+}
+
+func (e *TimeoutError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("timed out after %s waiting for %s: %s", e.Timeout, e.Description, e.Err)
+	}
+	return fmt.Sprintf("timed out after %s waiting for %s", e.Timeout, e.Description)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// DegradedError is returned when the server is running with problems.
+type DegradedError struct {
+	Problems []string
+}
+
+func (e *DegradedError) Error() string {
+	return "server is degraded: " + strings.Join(e.Problems, ", ")
+}
+
+// GetExitCode returns the code the tools should exit with for an error returned by a command.
+func GetExitCode(err error) int {
+	var cmdErr *CmdError
+	var notRunningErr *NotRunningError
+	var configErr *ConfigError
+	var degradedErr *DegradedError
+	switch {
+	case errors.As(err, &cmdErr) && cmdErr.Interactive && cmdErr.ExitCode > 0:
+		return cmdErr.ExitCode
+	case errors.As(err, &notRunningErr):
+		return ExitNotRunning
+	case errors.As(err, &configErr):
+		return ExitConfigInvalid
+	case errors.As(err, &degradedErr):
+		return ExitDegraded
+	}
+	return ExitFailure
+}
+
+// IsSilentError returns whether the error has already been shown to the user by an interactive command
+// or in a status output and only needs to be reflected in the exit code.
+func IsSilentError(err error) bool {
+	var cmdErr *CmdError
+	var degradedErr *DegradedError
+	return errors.As(err, &cmdErr) && cmdErr.Interactive && cmdErr.ExitCode > 0 || errors.As(err, &degradedErr)
+}
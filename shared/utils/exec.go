@@ -1,9 +1,9 @@
 package utils
 
 import (
-	"bufio"
+	"bytes"
 	"fmt"
-	"log"
+	"io"
 	"os"
 	"os/exec"
 	"strings"
@@ -11,22 +11,12 @@ import (
 	"github.com/uyuni-project/uyuni-tools/shared/types"
 )
 
-func Exec(globalFlags *types.GlobalFlags, interactive bool, tty bool, env []string, args ...string) {
-	command, podName := GetPodName(true)
-
-	commandArgs := []string{"exec"}
-	if interactive {
-		commandArgs = append(commandArgs, "-i")
-	}
-	if tty {
-		commandArgs = append(commandArgs, "-t")
-	}
-	commandArgs = append(commandArgs, podName)
-
-	if command == "kubectl" {
-		commandArgs = append(commandArgs, "-c", "uyuni", "--")
-	}
+// FqdnCommand prints the FQDN configured in the server container.
+const FqdnCommand = "sed -n 's/^java.hostname *= *//p' /etc/rhn/rhn.conf"
 
+// GetShellArgs returns the arguments to run a command in a container using `sh -c`.
+// The env values without '=' are taken from the local environment.
+func GetShellArgs(env []string, args []string) []string {
 	newEnv := []string{}
 	for _, envValue := range env {
 		if !strings.Contains(envValue, "=") {
@@ -37,42 +27,96 @@ func Exec(globalFlags *types.GlobalFlags, interactive bool, tty bool, env []stri
 			newEnv = append(newEnv, envValue)
 		}
 	}
+
+	commandArgs := []string{}
 	if len(newEnv) > 0 {
 		commandArgs = append(commandArgs, "env")
 		commandArgs = append(commandArgs, newEnv...)
 	}
-	commandArgs = append(commandArgs, "sh", "-c", strings.Join(args, " "))
-	if globalFlags.Verbose {
-		fmt.Printf("> Running: %s %s\n", command, strings.Join(commandArgs, " "))
-	}
-	runCmd := exec.Command(command, commandArgs...)
+	return append(commandArgs, "sh", "-c", strings.Join(args, " "))
+}
+
+// RunInteractiveCmd runs a command with the standard input and output attached.
+// If the command fails, the returned CmdError is flagged as interactive for the tool to exit with the command's code.
+func RunInteractiveCmd(command string, args []string) error {
+	runCmd := exec.Command(command, args...)
 	runCmd.Stdout = os.Stdout
 	runCmd.Stdin = os.Stdin
 
 	// Filter out kubectl line about terminated exit code
-	stderr, err := runCmd.StderrPipe()
+	stderr := &lineFilterWriter{out: os.Stderr, prefix: []byte("command terminated with exit code")}
+	runCmd.Stderr = stderr
+	err := GetRunner().Run(runCmd)
+	stderr.Flush()
 	if err != nil {
-		log.Fatal(err)
-	}
-	if err = runCmd.Start(); err != nil {
-		log.Fatal(err)
+		cmdErr := NewCmdError("", runCmd, nil, err)
+		// A command which couldn't be started didn't show anything
+		cmdErr.Interactive = cmdErr.ExitCode >= 0
+		return cmdErr
 	}
-	scanner := bufio.NewScanner(stderr)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if !strings.HasPrefix(line, "command terminated with exit code") {
-			fmt.Fprintln(os.Stderr, line)
+	return nil
+}
+
+// lineFilterWriter writes the lines not starting with prefix to out.
+type lineFilterWriter struct {
+	out    io.Writer
+	prefix []byte
+	buffer []byte
+}
+
+func (w *lineFilterWriter) Write(p []byte) (int, error) {
+	w.buffer = append(w.buffer, p...)
+	for {
+		index := bytes.IndexByte(w.buffer, '\n')
+		if index < 0 {
+			return len(p), nil
 		}
+		w.writeLine(w.buffer[:index+1])
+		w.buffer = w.buffer[index+1:]
 	}
+}
 
-	if scanner.Err() != nil {
-		log.Fatal(scanner.Err())
+// Flush writes the last line if it isn't terminated by a new line.
+func (w *lineFilterWriter) Flush() {
+	if len(w.buffer) > 0 {
+		w.writeLine(w.buffer)
+		w.buffer = nil
 	}
-	if err = runCmd.Wait(); err != nil {
+}
+
+func (w *lineFilterWriter) writeLine(line []byte) {
+	if !bytes.HasPrefix(line, w.prefix) {
+		w.out.Write(line)
+	}
+}
+
+// RunServerCmd runs a shell command in the server container and returns a CmdError with errMessage on error.
+// If stdin is not nil, it is passed as the standard input of the command.
+func RunServerCmd(backend types.Backend, command string, stdin io.Reader, errMessage string) error {
+	cmd, err := backend.Command("sh", "-c", command)
+	if err != nil {
+		return err
+	}
+	cmd.Stdin = stdin
+	if out, err := GetRunner().CombinedOutput(cmd); err != nil {
+		return NewCmdError(errMessage, cmd, out, err)
+	}
+	return nil
+}
+
+// GetServerCmdOutput runs a shell command in the server container and returns its trimmed output.
+func GetServerCmdOutput(backend types.Backend, command string) (string, error) {
+	cmd, err := backend.Command("sh", "-c", command)
+	if err != nil {
+		return "", err
+	}
+	out, err := GetQueryRunner().Output(cmd)
+	if err != nil {
+		var stderr []byte
 		if exitErr, ok := err.(*exec.ExitError); ok {
-			os.Exit(exitErr.ExitCode())
-		} else {
-			log.Fatal(err)
+			stderr = exitErr.Stderr
 		}
+		return "", NewCmdError(fmt.Sprintf("failed to run %s in the server container", command), cmd, stderr, err)
 	}
+	return strings.TrimSpace(string(out)), nil
 }
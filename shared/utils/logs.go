@@ -0,0 +1,312 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+)
+
+// LogComponent lists where the logs of a server component are.
+type LogComponent struct {
+	// Units are the systemd units writing to the journal.
+	Units []string
+	// Files are the log files in the var-log volume.
+	Files []string
+}
+
+// LogComponents are the server components with logs, by name.
+var LogComponents = map[string]LogComponent{
+	"tomcat": {
+		Units: []string{"tomcat.service"},
+		Files: []string{"/var/log/rhn/rhn_web_ui.log"},
+	},
+	"taskomatic": {
+		Units: []string{"taskomatic.service"},
+		Files: []string{"/var/log/rhn/rhn_taskomatic_daemon.log"},
+	},
+	"salt": {
+		Units: []string{"salt-master.service", "salt-api.service"},
+		Files: []string{"/var/log/salt/master"},
+	},
+	"apache": {
+		Units: []string{"apache2.service"},
+		Files: []string{"/var/log/apache2/error_log"},
+	},
+	"postgres": {
+		Units: []string{"postgresql.service"},
+	},
+	"setup": {
+		Files: []string{"/var/log/susemanager_setup.log"},
+	},
+}
+
+// LogComponentNames returns the sorted names of the LogComponents.
+func LogComponentNames() []string {
+	names := []string{}
+	for name := range LogComponents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseLogsSince parses the time of the oldest log line to show.
+// The value is either a duration before now, like 10m or 2h, or a RFC 3339 time.
+func ParseLogsSince(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if duration, err := time.ParseDuration(value); err == nil && duration >= 0 {
+		return time.Now().Add(-duration), nil
+	}
+	since, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, &ConfigError{
+			Message: fmt.Sprintf("invalid since value %s, expected a duration like 10m or a time like 2006-01-02T15:04:05Z", value),
+		}
+	}
+	return since, nil
+}
+
+// logSource is a command printing logs of the server container.
+type logSource struct {
+	// prefix is added to the lines not telling where they come from, like those of log files.
+	prefix string
+	cmd    *exec.Cmd
+}
+
+// ShowComponentLogs writes the logs of the server components to out, merged by their timestamps.
+// The journal of all the components units is read at once and each log file is read with tail.
+// When following the logs, the lines are written as they come.
+func ShowComponentLogs(backend types.Backend, components []string, options types.LogsOptions, out io.Writer) error {
+	sources, err := getLogSources(backend, components, options)
+	if err != nil {
+		return err
+	}
+	if options.Follow {
+		return followLogs(sources, options, out)
+	}
+
+	lines := []logLine{}
+	for _, source := range sources {
+		output, err := GetQueryRunner().Output(source.cmd)
+		if err != nil {
+			// A missing log file only means the component hasn't written anything yet
+			if source.prefix != "" {
+				log.Printf("Skipping %s: %s\n", strings.TrimSuffix(source.prefix, ": "), err)
+				continue
+			}
+			return NewCmdError("failed to read the server logs", source.cmd, nil, err)
+		}
+		lines = append(lines, parseLogLines(output, source.prefix, options.Since)...)
+	}
+
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].time.Before(lines[j].time) })
+	if options.Tail >= 0 && len(lines) > options.Tail {
+		lines = lines[len(lines)-options.Tail:]
+	}
+	for _, line := range lines {
+		fmt.Fprintln(out, line.text)
+	}
+	return nil
+}
+
+func getLogSources(backend types.Backend, components []string, options types.LogsOptions) ([]logSource, error) {
+	units := []string{}
+	files := []string{}
+	for _, name := range components {
+		component, ok := LogComponents[name]
+		if !ok {
+			return nil, &ConfigError{
+				Message: fmt.Sprintf("unknown component %s, possible values are: %s", name, strings.Join(LogComponentNames(), ", ")),
+			}
+		}
+		for _, unit := range component.Units {
+			if !Contains(units, unit) {
+				units = append(units, unit)
+			}
+		}
+		for _, file := range component.Files {
+			if !Contains(files, file) {
+				files = append(files, file)
+			}
+		}
+	}
+
+	sources := []logSource{}
+	if len(units) > 0 {
+		args := []string{"journalctl", "--no-pager", "--output=short-iso"}
+		for _, unit := range units {
+			args = append(args, "--unit="+unit)
+		}
+		if !options.Since.IsZero() {
+			args = append(args, fmt.Sprintf("--since=@%d", options.Since.Unix()))
+		}
+		if options.Tail >= 0 {
+			args = append(args, "--lines="+strconv.Itoa(options.Tail))
+		}
+		if options.Follow {
+			args = append(args, "--follow")
+		}
+		cmd, err := backend.Command(args...)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, logSource{cmd: cmd})
+	}
+
+	for _, file := range files {
+		lines := "+1"
+		if options.Tail >= 0 {
+			lines = strconv.Itoa(options.Tail)
+		}
+		args := []string{"tail", "-n", lines}
+		if options.Follow {
+			// Also wait for the files which don't exist yet
+			args = append(args, "-F")
+		}
+		cmd, err := backend.Command(append(args, file)...)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, logSource{prefix: filepath.Base(file) + ": ", cmd: cmd})
+	}
+	return sources, nil
+}
+
+// followLogs runs all the sources at once and writes their lines as they come.
+func followLogs(sources []logSource, options types.LogsOptions, out io.Writer) error {
+	var mutex sync.Mutex
+	errs := make([]error, len(sources))
+	var group sync.WaitGroup
+	for i, source := range sources {
+		writer := &logLineWriter{out: out, mutex: &mutex, prefix: source.prefix, since: options.Since}
+		source.cmd.Stdout = writer
+		source.cmd.Stderr = os.Stderr
+		group.Add(1)
+		go func(i int, cmd *exec.Cmd) {
+			defer group.Done()
+			if err := GetQueryRunner().Run(cmd); err != nil {
+				errs[i] = NewCmdError("failed to follow the server logs", cmd, nil, err)
+			}
+			writer.Flush()
+		}(i, source.cmd)
+	}
+	group.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// logLine is a log line with the time it was written at.
+type logLine struct {
+	time time.Time
+	text string
+}
+
+// parseLogLines splits the output of a log source into lines, skipping those older than since.
+// The lines without timestamp, like stack traces, get the time of the previous line.
+func parseLogLines(output []byte, prefix string, since time.Time) []logLine {
+	lines := []logLine{}
+	var lineTime time.Time
+	for _, text := range strings.Split(strings.TrimRight(string(output), "\n"), "\n") {
+		if text == "" {
+			continue
+		}
+		if parsed, ok := parseLogTime(text); ok {
+			lineTime = parsed
+		}
+		if !since.IsZero() && lineTime.Before(since) {
+			continue
+		}
+		lines = append(lines, logLine{time: lineTime, text: prefix + text})
+	}
+	return lines
+}
+
+// parseLogTime returns the timestamp at the beginning of a log line.
+// The timestamps without time zone, like those of the log files, are in the local time zone.
+func parseLogTime(line string) (time.Time, bool) {
+	// journalctl short-iso output
+	field, _, _ := strings.Cut(line, " ")
+	if parsed, err := time.Parse("2006-01-02T15:04:05-0700", field); err == nil {
+		return parsed, true
+	}
+
+	// apache error_log: [Tue Jul 25 10:00:00.123456 2023]
+	if strings.HasPrefix(line, "[") {
+		if end := strings.Index(line, "]"); end > 0 {
+			if parsed, err := time.ParseInLocation("Mon Jan 02 15:04:05.000000 2006", line[1:end], time.Local); err == nil {
+				return parsed, true
+			}
+		}
+	}
+
+	// tomcat, taskomatic, salt and setup logs: 2023-07-25 10:00:00,123
+	if len(line) >= 19 {
+		for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02T15:04:05"} {
+			if parsed, err := time.ParseInLocation(layout, line[:19], time.Local); err == nil {
+				return parsed, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// logLineWriter writes the complete lines of a followed log source to a shared output.
+type logLineWriter struct {
+	out      io.Writer
+	mutex    *sync.Mutex
+	prefix   string
+	since    time.Time
+	lineTime time.Time
+	buffer   bytes.Buffer
+}
+
+func (w *logLineWriter) Write(p []byte) (int, error) {
+	w.buffer.Write(p)
+	for {
+		line, err := w.buffer.ReadString('\n')
+		if err != nil {
+			// Keep the incomplete line for the next write
+			w.buffer.WriteString(line)
+			return len(p), nil
+		}
+		w.writeLine(strings.TrimSuffix(line, "\n"))
+	}
+}
+
+// Flush writes the remaining incomplete line.
+func (w *logLineWriter) Flush() {
+	if w.buffer.Len() > 0 {
+		w.writeLine(w.buffer.String())
+		w.buffer.Reset()
+	}
+}
+
+func (w *logLineWriter) writeLine(line string) {
+	if parsed, ok := parseLogTime(line); ok {
+		w.lineTime = parsed
+	}
+	if !w.since.IsZero() && w.lineTime.Before(w.since) {
+		return
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	fmt.Fprintln(w.out, w.prefix+line)
+}
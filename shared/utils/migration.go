@@ -0,0 +1,199 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// GetSshAuthSocket returns the path to the SSH agent socket or an error if not defined.
+func GetSshAuthSocket() (string, error) {
+	path := os.Getenv("SSH_AUTH_SOCK")
+	if len(path) == 0 {
+		return "", &ConfigError{Message: "SSH_AUTH_SOCK is not defined, start an ssh agent and try again"}
+	}
+	return path, nil
+}
+
+// GetSshPaths returns the paths to the SSH config and known_hosts files to mount in the migration container.
+func GetSshPaths() (string, string, error) {
+	homedir, err := os.UserHomeDir()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to find home directory to look for SSH config: %w", err)
+	}
+	sshConfigPath := filepath.Join(homedir, ".ssh", "config")
+	sshKnownhostsPath := filepath.Join(homedir, ".ssh", "known_hosts")
+	return sshConfigPath, sshKnownhostsPath, nil
+}
+
+// MigrationStatePath is the path where the migration state folder is mounted in the migration container.
+const MigrationStatePath = "/var/lib/uyuni-tools/state"
+
+// databaseFolder is only synchronized in the final phase as it can't be copied while the source server runs.
+const databaseFolder = "/var/lib/pgsql"
+
+// Steps of the final migration phase recorded in the state folder to resume after an interruption.
+const (
+	// MigrationSynchronizedStep is done once all the data has been synchronized.
+	MigrationSynchronizedStep = "synchronized"
+	// MigrationServiceStep is done once the server service has been generated.
+	MigrationServiceStep = "service"
+)
+
+const migrationStepsFile = "steps"
+
+// migrationDataFile is the file of the state folder where the migration script writes the source server data.
+const migrationDataFile = "data"
+
+// GetMigrationStateDir creates and returns the folder storing the state of the migration of a source server.
+// The state allows an interrupted synchronization phase to resume instead of restarting.
+// An empty stateDir means the default folder for the user running the tools.
+func GetMigrationStateDir(stateDir string, sourceFqdn string) (string, error) {
+	if stateDir == "" {
+		var err error
+		if stateDir, err = GetDefaultStateDir("migration", "--state-dir"); err != nil {
+			return "", err
+		}
+	}
+	path := filepath.Join(stateDir, sourceFqdn)
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return "", fmt.Errorf("failed to create migration state folder %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// IsMigrationStepDone returns whether a step of the final migration phase is recorded as done in the state folder.
+func IsMigrationStepDone(stateDir string, step string) bool {
+	content, err := os.ReadFile(filepath.Join(stateDir, migrationStepsFile))
+	return err == nil && Contains(strings.Split(string(content), "\n"), step)
+}
+
+// SetMigrationStepDone records a step of the final migration phase as done in the state folder.
+func SetMigrationStepDone(stateDir string, step string) error {
+	path := filepath.Join(stateDir, migrationStepsFile)
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	if _, err := file.WriteString(step + "\n"); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return file.Close()
+}
+
+// ReadMigrationTimezone returns the timezone of the source server extracted by the migration script in the state folder.
+func ReadMigrationTimezone(stateDir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(stateDir, migrationDataFile))
+	if err != nil {
+		return "", fmt.Errorf("failed to read data extracted from source host: %w", err)
+	}
+	return ParseMigrationTimezone(string(data))
+}
+
+// ParseMigrationTimezone returns the timezone of the source server in the output of the migration script.
+func ParseMigrationTimezone(data string) (string, error) {
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "Timezone=") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "Timezone=")), nil
+		}
+	}
+	return "", errors.New("no timezone in the data extracted from source host")
+}
+
+// GenerateMigrationScript creates a temporary folder with the migrate.sh script to run in the migration container.
+// The caller needs to remove the returned folder, it is already removed if an error is returned.
+func GenerateMigrationScript(sourceFqdn string, kubernetes bool, final bool) (string, error) {
+	script, err := RenderMigrationScript(sourceFqdn, kubernetes, final)
+	if err != nil {
+		return "", err
+	}
+
+	scriptDir, err := os.MkdirTemp("", "uyuniadm-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(scriptDir, "migrate.sh"), []byte(script), 0555); err != nil {
+		os.RemoveAll(scriptDir)
+		return "", fmt.Errorf("failed to write migration script: %w", err)
+	}
+	return scriptDir, nil
+}
+
+// RenderMigrationScript returns the content of the migrate.sh script to run in the migration container.
+//
+// If final is false, the script synchronizes the volumes except the database while the source server is running.
+// This pre-synchronization can be run several times to reduce the time needed by the final phase.
+// If final is true, the script stops the source server services and synchronizes all the volumes.
+// The data extracted from the source server are written in the state folder and printed to be read from the logs.
+func RenderMigrationScript(sourceFqdn string, kubernetes bool, final bool) (string, error) {
+	const scriptTemplate = `#!/bin/bash
+set -e
+STATE={{ .StatePath }}/{{ if .Final }}final{{ else }}presync{{ end }}
+touch $STATE
+
+{{ if .Final }}
+echo "Stopping the services on {{ .SourceFqdn }}"
+ssh -A {{ .SourceFqdn }} sudo spacewalk-service stop
+ssh -A {{ .SourceFqdn }} sudo systemctl stop postgresql
+{{ end }}
+for folder in {{range .Volumes}}{{.}} {{end}};
+do
+  if grep -qx "$folder" $STATE; then
+    echo "Skipping $folder: already synchronized"
+    continue
+  fi
+  rsync -e "ssh -A " --rsync-path='sudo rsync' -avz --partial --delete {{.SourceFqdn}}:$folder/ $folder;
+  echo "$folder" >> $STATE
+done;
+
+{{ if .Final }}
+rm -f /srv/www/htdocs/pub/RHN-ORG-TRUSTED-SSL-CERT;
+ln -s /etc/pki/trust/anchors/LOCAL-RHN-ORG-TRUSTED-SSL-CERT /srv/www/htdocs/pub/RHN-ORG-TRUSTED-SSL-CERT;
+
+ssh {{ .SourceFqdn }} timedatectl show -p Timezone >{{ .StatePath }}/{{ .DataFile }}
+cat {{ .StatePath }}/{{ .DataFile }}
+
+{{ if .Kubernetes }}
+grep -q '^server.no_ssl' /etc/rhn/rhn.conf || echo 'server.no_ssl = 1' >> /etc/rhn/rhn.conf;
+sed 's/address=[^:]*:/address=uyuni:/' -i /etc/rhn/taskomatic.conf;
+sed 's/address=[^:]*:/address=uyuni:/' -i /etc/sysconfig/tomcat;
+{{ end }}
+{{ end }}
+# The phase is complete, the next run will synchronize everything again
+rm $STATE
+echo "DONE"`
+
+	volumes := []string{}
+	for _, path := range VOLUMES {
+		if final || path != databaseFolder {
+			volumes = append(volumes, path)
+		}
+	}
+
+	model := struct {
+		Volumes    []string
+		SourceFqdn string
+		Kubernetes bool
+		Final      bool
+		StatePath  string
+		DataFile   string
+	}{
+		Volumes:    volumes,
+		SourceFqdn: sourceFqdn,
+		Kubernetes: kubernetes,
+		Final:      final,
+		StatePath:  MigrationStatePath,
+		DataFile:   migrationDataFile,
+	}
+
+	t := template.Must(template.New("script").Parse(scriptTemplate))
+	var script strings.Builder
+	if err := t.Execute(&script, model); err != nil {
+		return "", fmt.Errorf("failed to generate migration script: %w", err)
+	}
+	return script.String(), nil
+}
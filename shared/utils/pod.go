@@ -28,3 +28,22 @@ var VOLUMES = map[string]string{
 	"etc-tls":             "/etc/pki/tls",
 	"ca-cert":             "/etc/pki/trust/anchors/",
 }
+
+// DefaultVolumeSize is the size in GiB of the volumes not listed in VOLUME_SIZES.
+const DefaultVolumeSize = 1
+
+// VOLUME_SIZES lists the sizes in GiB of the volumes needing more space than DefaultVolumeSize.
+var VOLUME_SIZES = map[string]int{
+	"var-pgsql":     50,
+	"var-spacewalk": 100,
+	"var-cache":     10,
+	"var-log":       10,
+}
+
+// GetVolumeSize returns the size in GiB needed by a volume.
+func GetVolumeSize(name string) int {
+	if size, ok := VOLUME_SIZES[name]; ok {
+		return size
+	}
+	return DefaultVolumeSize
+}
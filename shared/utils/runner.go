@@ -0,0 +1,219 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+)
+
+// Runner runs the external commands of the tools: podman, kubectl, helm, systemctl...
+// The commands are prepared with exec.Command, but only started by the runner.
+type Runner interface {
+	// Run runs the command with the standard input and outputs set by the caller.
+	Run(cmd *exec.Cmd) error
+	// Output runs the command and returns its standard output.
+	Output(cmd *exec.Cmd) ([]byte, error)
+	// CombinedOutput runs the command and returns its standard output and error.
+	CombinedOutput(cmd *exec.Cmd) ([]byte, error)
+}
+
+var currentRunner Runner = ExecRunner{}
+
+// GetRunner returns the runner used for all the commands.
+func GetRunner() Runner {
+	return currentRunner
+}
+
+// SetRunner replaces the runner used for all the commands and returns the previous one.
+func SetRunner(runner Runner) Runner {
+	previous := currentRunner
+	currentRunner = runner
+	return previous
+}
+
+// GetQueryRunner returns the runner for the commands only reading the state of the machine or the server,
+// like podman ps or systemctl list-unit-files.
+// With --dry-run, they are still run for the flows to take the same decisions as without it.
+func GetQueryRunner() Runner {
+	if dryRun, ok := currentRunner.(DryRunRunner); ok && dryRun.Queries != nil {
+		return dryRun.Queries
+	}
+	return currentRunner
+}
+
+// IsDryRun returns whether the commands changing the state are only printed.
+func IsDryRun() bool {
+	_, ok := currentRunner.(DryRunRunner)
+	return ok
+}
+
+// CmdExitCode returns the exit code of a command from the error returned by a runner.
+// It is 0 if there is no error and -1 if the command couldn't be started.
+func CmdExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr interface{ ExitCode() int }
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// NewRunner returns the runner matching the global flags.
+// The commands are only printed with --dry-run and printed before running them with --verbose.
+func NewRunner(globalFlags *types.GlobalFlags) Runner {
+	runner := ExecRunner{}
+	if globalFlags.Verbose {
+		runner.Out = os.Stdout
+	}
+	if globalFlags.DryRun {
+		return DryRunRunner{Out: os.Stdout, Queries: runner}
+	}
+	return runner
+}
+
+// ExecRunner runs the commands on the machine.
+type ExecRunner struct {
+	// Out receives the command lines before they are run, nothing is printed if nil.
+	Out io.Writer
+}
+
+func (r ExecRunner) Run(cmd *exec.Cmd) error {
+	r.print(cmd)
+	return cmd.Run()
+}
+
+func (r ExecRunner) Output(cmd *exec.Cmd) ([]byte, error) {
+	r.print(cmd)
+	return cmd.Output()
+}
+
+func (r ExecRunner) CombinedOutput(cmd *exec.Cmd) ([]byte, error) {
+	r.print(cmd)
+	return cmd.CombinedOutput()
+}
+
+func (r ExecRunner) print(cmd *exec.Cmd) {
+	if r.Out != nil {
+		fmt.Fprintf(r.Out, "> Running: %s\n", strings.Join(cmd.Args, " "))
+	}
+}
+
+// DryRunRunner prints the commands instead of running them.
+// The printed commands succeed without output: the commands reading the state
+// need to go through GetQueryRunner to be run by Queries.
+type DryRunRunner struct {
+	Out io.Writer
+	// Queries runs the commands from GetQueryRunner, they are printed like the others if nil.
+	Queries Runner
+}
+
+func (r DryRunRunner) Run(cmd *exec.Cmd) error {
+	fmt.Fprintf(r.Out, "> Would run: %s\n", strings.Join(cmd.Args, " "))
+	return nil
+}
+
+func (r DryRunRunner) Output(cmd *exec.Cmd) ([]byte, error) {
+	return []byte{}, r.Run(cmd)
+}
+
+func (r DryRunRunner) CombinedOutput(cmd *exec.Cmd) ([]byte, error) {
+	return []byte{}, r.Run(cmd)
+}
+
+// FakeResult is the scripted result of the commands run by a FakeRunner.
+type FakeResult struct {
+	// Prefix is the start of the command lines the result applies to, like "podman volume inspect".
+	Prefix   string
+	Output   string
+	ExitCode int
+	// Times is the number of commands the result is used for, 0 for no limit.
+	// It is set to -1 once the result has been used that many times.
+	Times int
+}
+
+// FakeExitError is returned by a FakeRunner for the commands with a non zero scripted exit code.
+type FakeExitError struct {
+	Code int
+}
+
+func (e *FakeExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+func (e *FakeExitError) ExitCode() int {
+	return e.Code
+}
+
+// FakeRunner records the commands instead of running them and returns the scripted results.
+// The first result with a prefix matching the command line is used,
+// the commands without matching result succeed without output.
+type FakeRunner struct {
+	Results []FakeResult
+	// Commands are the command lines run, in order.
+	Commands []string
+	// Stdins are the standard inputs passed to the commands, empty if none, in the same order.
+	Stdins []string
+
+	mutex sync.Mutex
+}
+
+func (r *FakeRunner) Run(cmd *exec.Cmd) error {
+	out, err := r.run(cmd)
+	if cmd.Stdout != nil {
+		cmd.Stdout.Write(out)
+	}
+	return err
+}
+
+func (r *FakeRunner) Output(cmd *exec.Cmd) ([]byte, error) {
+	return r.run(cmd)
+}
+
+func (r *FakeRunner) CombinedOutput(cmd *exec.Cmd) ([]byte, error) {
+	return r.run(cmd)
+}
+
+func (r *FakeRunner) run(cmd *exec.Cmd) ([]byte, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	line := strings.Join(cmd.Args, " ")
+	stdin := ""
+	// Don't wait for the user input of the interactive commands
+	if _, isFile := cmd.Stdin.(*os.File); cmd.Stdin != nil && !isFile {
+		content, err := io.ReadAll(cmd.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		stdin = string(content)
+	}
+	r.Commands = append(r.Commands, line)
+	r.Stdins = append(r.Stdins, stdin)
+
+	for i := range r.Results {
+		result := &r.Results[i]
+		if !strings.HasPrefix(line, result.Prefix) || result.Times < 0 {
+			continue
+		}
+		if result.Times > 0 {
+			result.Times--
+			if result.Times == 0 {
+				// Exhausted: skip it for the next commands
+				result.Times = -1
+			}
+		}
+		if result.ExitCode != 0 {
+			return []byte(result.Output), &FakeExitError{Code: result.ExitCode}
+		}
+		return []byte(result.Output), nil
+	}
+	return []byte{}, nil
+}
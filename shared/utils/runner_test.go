@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+)
+
+func TestRunnersPrint(t *testing.T) {
+	tests := []struct {
+		name     string
+		runner   func(out *strings.Builder) Runner
+		expected string
+	}{
+		{"exec", func(out *strings.Builder) Runner { return ExecRunner{} }, ""},
+		{"verbose exec", func(out *strings.Builder) Runner { return ExecRunner{Out: out} }, "> Running: true --flag\n"},
+		{"dry run", func(out *strings.Builder) Runner { return DryRunRunner{Out: out} }, "> Would run: true --flag\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out strings.Builder
+			if err := test.runner(&out).Run(exec.Command("true", "--flag")); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if out.String() != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, out.String())
+			}
+		})
+	}
+}
+
+func TestNewRunner(t *testing.T) {
+	if runner, ok := NewRunner(&types.GlobalFlags{DryRun: true, Verbose: true}).(DryRunRunner); !ok {
+		t.Error("expected a DryRunRunner with --dry-run")
+	} else if queries, ok := runner.Queries.(ExecRunner); !ok || queries.Out == nil {
+		t.Error("expected the queries to be run and printed with --dry-run --verbose")
+	}
+	if runner, ok := NewRunner(&types.GlobalFlags{Verbose: true}).(ExecRunner); !ok || runner.Out == nil {
+		t.Error("expected an ExecRunner printing the commands with --verbose")
+	}
+	if runner, ok := NewRunner(&types.GlobalFlags{}).(ExecRunner); !ok || runner.Out != nil {
+		t.Error("expected a silent ExecRunner by default")
+	}
+}
+
+func TestGetQueryRunner(t *testing.T) {
+	queries := &FakeRunner{}
+	var out strings.Builder
+	previous := SetRunner(DryRunRunner{Out: &out, Queries: queries})
+	defer SetRunner(previous)
+
+	if !IsDryRun() {
+		t.Error("expected a dry run")
+	}
+	if err := GetQueryRunner().Run(exec.Command("podman", "ps")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := GetRunner().Run(exec.Command("podman", "kill", "uyuni-server")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(queries.Commands) != 1 || queries.Commands[0] != "podman ps" {
+		t.Errorf("expected only the query to be run, got %v", queries.Commands)
+	}
+	if out.String() != "> Would run: podman kill uyuni-server\n" {
+		t.Errorf("expected only the change to be printed, got %q", out.String())
+	}
+}
@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+)
+
+// CaCertPath is the path of the CA certificate trusted in the server container.
+const CaCertPath = "/etc/pki/trust/anchors/LOCAL-RHN-ORG-TRUSTED-SSL-CERT"
+
+// PubCaCertPath is the path of the CA certificate published for the clients in the server container.
+const PubCaCertPath = "/srv/www/htdocs/pub/RHN-ORG-TRUSTED-SSL-CERT"
+
+// ServerCertPath is the path of the server certificate in the server container.
+const ServerCertPath = "/etc/pki/tls/certs/spacewalk.crt"
+
+// RestartSslServicesCommand restarts the services using the certificates in the server container.
+const RestartSslServicesCommand = "systemctl restart postgresql && spacewalk-service restart"
+
+// ReadCertificates returns the certificates contained in a PEM file.
+func ReadCertificates(path string) ([]*x509.Certificate, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate file %s: %w", path, err)
+	}
+	certificates, err := ParseCertificates(content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificate file %s: %w", path, err)
+	}
+	if len(certificates) == 0 {
+		return nil, fmt.Errorf("no certificate found in %s", path)
+	}
+	return certificates, nil
+}
+
+// ParseCertificates returns the certificates contained in PEM data.
+func ParseCertificates(content []byte) ([]*x509.Certificate, error) {
+	certificates := []*x509.Certificate{}
+	for block, rest := pem.Decode(content); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate: %w", err)
+		}
+		certificates = append(certificates, certificate)
+	}
+	return certificates, nil
+}
+
+// VerifyCertificate checks the server certificate is valid, signed by the CA through the intermediate
+// certificates and matches the FQDN and all the CNAMEs.
+func VerifyCertificate(ca []*x509.Certificate, intermediates []*x509.Certificate, server *x509.Certificate,
+	fqdn string, cnames []string) error {
+	roots := x509.NewCertPool()
+	for _, certificate := range ca {
+		roots.AddCert(certificate)
+	}
+	intermediatesPool := x509.NewCertPool()
+	for _, certificate := range intermediates {
+		intermediatesPool.AddCert(certificate)
+	}
+
+	for _, name := range append([]string{fqdn}, cnames...) {
+		options := x509.VerifyOptions{
+			DNSName:       name,
+			Roots:         roots,
+			Intermediates: intermediatesPool,
+		}
+		if _, err := server.Verify(options); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckCertificateFiles validates the SSL certificate files before using them for the server.
+// The server certificate needs to be valid for the FQDN and CNAMEs and the key needs to match it.
+func CheckCertificateFiles(caPath string, intermediatePaths []string, certPath string, keyPath string,
+	fqdn string, cnames []string) error {
+	ca, err := ReadCertificates(caPath)
+	if err != nil {
+		return err
+	}
+	intermediates := []*x509.Certificate{}
+	for _, path := range intermediatePaths {
+		certificates, err := ReadCertificates(path)
+		if err != nil {
+			return err
+		}
+		intermediates = append(intermediates, certificates...)
+	}
+	server, err := ReadCertificates(certPath)
+	if err != nil {
+		return err
+	}
+
+	// The server certificate file may contain the intermediate certificates
+	intermediates = append(intermediates, server[1:]...)
+	if err := VerifyCertificate(ca, intermediates, server[0], fqdn, cnames); err != nil {
+		return fmt.Errorf("invalid server certificate %s: %w", certPath, err)
+	}
+
+	certContent, err := os.ReadFile(certPath)
+	if err != nil {
+		return fmt.Errorf("failed to read certificate file %s: %w", certPath, err)
+	}
+	keyContent, err := os.ReadFile(keyPath)
+	if err != nil {
+		return fmt.Errorf("failed to read key file %s: %w", keyPath, err)
+	}
+	if _, err := tls.X509KeyPair(certContent, keyContent); err != nil {
+		return fmt.Errorf("server key %s doesn't match certificate %s: %w", keyPath, certPath, err)
+	}
+	return nil
+}
@@ -0,0 +1,320 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"sigs.k8s.io/yaml"
+)
+
+// Thresholds above which the server is reported as degraded.
+const (
+	volumeUsageLimit       = 90
+	certificateExpiryLimit = 30
+)
+
+// GetServerStatus collects the health overview of the server managed by backend.
+// The parts that can't be read are reported as problems, making the server degraded.
+// If the server isn't running, the status is returned with a NotRunningError.
+func GetServerStatus(backend types.Backend, viper *viper.Viper) (*types.ServerStatus, error) {
+	status := types.ServerStatus{
+		Backend:      backend.Name(),
+		Services:     []types.ServiceStatus{},
+		Volumes:      []types.VolumeStatus{},
+		Certificates: []types.CertificateStatus{},
+		Problems:     []string{},
+	}
+
+	container, err := backend.GetContainerStatus()
+	if err != nil {
+		var notRunningErr *NotRunningError
+		if errors.As(err, &notRunningErr) {
+			status.Container.State = "not found"
+		}
+		status.Problems = append(status.Problems, err.Error())
+		return &status, err
+	}
+	status.Container = *container
+	status.Tag = getImageTag(container.Image)
+	if !container.Running {
+		err := &NotRunningError{Name: container.Name, Backend: backend.Name()}
+		status.Problems = append(status.Problems, err.Error())
+		return &status, err
+	}
+	if container.StartedAt != nil {
+		status.Uptime = formatUptime(time.Since(*container.StartedAt))
+	}
+
+	addProblem := func(format string, args ...interface{}) {
+		status.Problems = append(status.Problems, fmt.Sprintf(format, args...))
+	}
+
+	if status.Services, err = getServicesStatus(backend); err != nil {
+		addProblem("failed to get the services state: %s", err)
+	}
+	for _, service := range status.Services {
+		if service.State != "active" {
+			addProblem("service %s is %s", service.Name, service.State)
+		}
+	}
+
+	if status.Volumes, err = getVolumesStatus(backend); err != nil {
+		addProblem("failed to get the volumes usage: %s", err)
+	}
+	for _, volume := range status.Volumes {
+		if volume.UsedPercent >= volumeUsageLimit {
+			addProblem("volume %s is %d%% full", volume.Name, volume.UsedPercent)
+		}
+	}
+
+	if status.Certificates, err = getCertificatesStatus(backend, viper); err != nil {
+		addProblem("failed to read the certificates: %s", err)
+	}
+	for _, certificate := range status.Certificates {
+		switch {
+		case certificate.DaysLeft < 0:
+			addProblem("%s certificate expired on %s", certificate.Name, certificate.NotAfter.Format(time.RFC3339))
+		case certificate.DaysLeft < certificateExpiryLimit:
+			addProblem("%s certificate expires in %d days", certificate.Name, certificate.DaysLeft)
+		}
+	}
+
+	return &status, nil
+}
+
+// getImageTag returns the tag of an image reference, empty if there is none.
+func getImageTag(image string) string {
+	if strings.Contains(image, "@") {
+		return ""
+	}
+	name := image[strings.LastIndex(image, "/")+1:]
+	if index := strings.LastIndex(name, ":"); index >= 0 {
+		return name[index+1:]
+	}
+	return ""
+}
+
+// getImageName returns an image reference without its tag.
+func getImageName(image string) string {
+	if tag := getImageTag(image); tag != "" {
+		return strings.TrimSuffix(image, ":"+tag)
+	}
+	return image
+}
+
+// formatUptime formats a duration in days, hours and minutes.
+func formatUptime(duration time.Duration) string {
+	minutes := int(duration.Minutes())
+	days := minutes / (24 * 60)
+	hours := minutes / 60 % 24
+	if days > 0 {
+		return fmt.Sprintf("%dd %dh %dm", days, hours, minutes%60)
+	}
+	return fmt.Sprintf("%dh %dm", hours, minutes%60)
+}
+
+// getServicesStatus returns the systemd active state of the ServerServices.
+func getServicesStatus(backend types.Backend) ([]types.ServiceStatus, error) {
+	args := append([]string{"systemctl", "show", "--property=Id,ActiveState"}, ServerServices...)
+	cmd, err := backend.Command(args...)
+	if err != nil {
+		return []types.ServiceStatus{}, err
+	}
+	out, err := GetQueryRunner().Output(cmd)
+	if err != nil {
+		return []types.ServiceStatus{}, NewCmdError("", cmd, nil, err)
+	}
+
+	// The properties of each unit are separated by an empty line
+	services := []types.ServiceStatus{}
+	for _, block := range strings.Split(strings.TrimSpace(string(out)), "\n\n") {
+		service := types.ServiceStatus{}
+		for _, line := range strings.Split(block, "\n") {
+			key, value, _ := strings.Cut(strings.TrimSpace(line), "=")
+			switch key {
+			case "Id":
+				service.Name = value
+			case "ActiveState":
+				service.State = value
+			}
+		}
+		if service.Name != "" {
+			services = append(services, service)
+		}
+	}
+	return services, nil
+}
+
+// getVolumesStatus returns the disk usage of the VOLUMES, sorted by name.
+func getVolumesStatus(backend types.Backend) ([]types.VolumeStatus, error) {
+	names := []string{}
+	paths := []string{}
+	for name, path := range VOLUMES {
+		names = append(names, name)
+		paths = append(paths, path)
+	}
+
+	args := append([]string{"df", "-B1", "--output=file,size,used"}, paths...)
+	cmd, err := backend.Command(args...)
+	if err != nil {
+		return []types.VolumeStatus{}, err
+	}
+	// df fails if one of the paths is missing, but still reports the other ones
+	out, err := GetQueryRunner().Output(cmd)
+	if len(out) == 0 && err != nil {
+		return []types.VolumeStatus{}, NewCmdError("", cmd, nil, err)
+	}
+
+	usage := map[string][]uint64{}
+	for _, line := range strings.Split(string(out), "\n")[1:] {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		size, sizeErr := strconv.ParseUint(fields[1], 10, 64)
+		used, usedErr := strconv.ParseUint(fields[2], 10, 64)
+		if sizeErr == nil && usedErr == nil {
+			usage[fields[0]] = []uint64{size, used}
+		}
+	}
+
+	volumes := []types.VolumeStatus{}
+	for i, name := range names {
+		values, ok := usage[paths[i]]
+		if !ok {
+			continue
+		}
+		volume := types.VolumeStatus{Name: name, Path: paths[i], Size: values[0], Used: values[1]}
+		if volume.Size > 0 {
+			volume.UsedPercent = int(volume.Used * 100 / volume.Size)
+		}
+		volumes = append(volumes, volume)
+	}
+	sort.Slice(volumes, func(i, j int) bool { return volumes[i].Name < volumes[j].Name })
+
+	if len(volumes) < len(names) {
+		return volumes, fmt.Errorf("%d volumes not found in the server container", len(names)-len(volumes))
+	}
+	return volumes, nil
+}
+
+// getCertificatesStatus returns the validity of the CA and server certificates.
+func getCertificatesStatus(backend types.Backend, viper *viper.Viper) ([]types.CertificateStatus, error) {
+	caContent, serverContent, err := backend.GetCertificates(viper)
+	if err != nil {
+		return []types.CertificateStatus{}, err
+	}
+
+	certificates := []types.CertificateStatus{}
+	for _, entry := range []struct {
+		name    string
+		content []byte
+	}{{"ca", caContent}, {"server", serverContent}} {
+		parsed, err := ParseCertificates(entry.content)
+		if err != nil {
+			return certificates, err
+		}
+		if len(parsed) == 0 {
+			return certificates, fmt.Errorf("no %s certificate found", entry.name)
+		}
+		certificates = append(certificates, types.CertificateStatus{
+			Name:     entry.name,
+			Subject:  parsed[0].Subject.CommonName,
+			NotAfter: parsed[0].NotAfter,
+			DaysLeft: int(time.Until(parsed[0].NotAfter).Hours() / 24),
+		})
+	}
+	return certificates, nil
+}
+
+// PrintServerStatus writes the server status to out as a table, or as JSON or YAML if format is json or yaml.
+func PrintServerStatus(status *types.ServerStatus, format string, out io.Writer) error {
+	switch format {
+	case "json":
+		content, err := json.MarshalIndent(status, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to convert the server status to JSON: %w", err)
+		}
+		_, err = fmt.Fprintln(out, string(content))
+		return err
+	case "yaml":
+		content, err := yaml.Marshal(status)
+		if err != nil {
+			return fmt.Errorf("failed to convert the server status to YAML: %w", err)
+		}
+		_, err = out.Write(content)
+		return err
+	case "table":
+	default:
+		return &ConfigError{Message: fmt.Sprintf("invalid output format %s, possible values are: table, json, yaml", format)}
+	}
+
+	writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(writer, "Backend:\t%s\n", status.Backend)
+	fmt.Fprintf(writer, "Container:\t%s (%s)\n", status.Container.Name, status.Container.State)
+	if status.Container.Image != "" {
+		fmt.Fprintf(writer, "Image:\t%s\n", getImageName(status.Container.Image))
+		fmt.Fprintf(writer, "Tag:\t%s\n", status.Tag)
+	}
+	if status.Uptime != "" {
+		fmt.Fprintf(writer, "Uptime:\t%s\n", status.Uptime)
+	}
+
+	if len(status.Services) > 0 {
+		fmt.Fprintln(writer, "\nSERVICE\tSTATE")
+		for _, service := range status.Services {
+			fmt.Fprintf(writer, "%s\t%s\n", service.Name, service.State)
+		}
+	}
+
+	if len(status.Volumes) > 0 {
+		fmt.Fprintln(writer, "\nVOLUME\tPATH\tSIZE\tUSED\tUSE%")
+		for _, volume := range status.Volumes {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%d%%\n", volume.Name, volume.Path,
+				formatBytes(volume.Size), formatBytes(volume.Used), volume.UsedPercent)
+		}
+	}
+
+	if len(status.Certificates) > 0 {
+		fmt.Fprintln(writer, "\nCERTIFICATE\tSUBJECT\tEXPIRES\tDAYS LEFT")
+		for _, certificate := range status.Certificates {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%d\n", certificate.Name, certificate.Subject,
+				certificate.NotAfter.Format(time.RFC3339), certificate.DaysLeft)
+		}
+	}
+
+	if len(status.Problems) > 0 {
+		fmt.Fprintln(writer, "\nStatus:\tdegraded")
+		for _, problem := range status.Problems {
+			fmt.Fprintf(writer, "  * %s\n", problem)
+		}
+	} else {
+		fmt.Fprintln(writer, "\nStatus:\thealthy")
+	}
+	return writer.Flush()
+}
+
+// formatBytes formats a size in bytes with a binary unit.
+func formatBytes(size uint64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	value := float64(size)
+	units := []string{"KiB", "MiB", "GiB", "TiB", "PiB"}
+	index := -1
+	for value >= unit && index < len(units)-1 {
+		value /= unit
+		index++
+	}
+	return fmt.Sprintf("%.1f%s", value, units[index])
+}
@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"text/template"
+)
+
+// WriteTemplate renders t with model into the file at path, creating it with perm permissions if needed.
+func WriteTemplate(t *template.Template, path string, perm os.FileMode, model interface{}) error {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, model); err != nil {
+		return err
+	}
+	return WriteFile(path, buf.Bytes(), perm)
+}
+
+// WriteFile writes content to the file at path, creating it with perm permissions if needed.
+// With the DryRunRunner, the file is only printed.
+func WriteFile(path string, content []byte, perm os.FileMode) error {
+	if dryRun, ok := GetRunner().(DryRunRunner); ok {
+		fmt.Fprintf(dryRun.Out, "> Would write %s:\n%s\n", path, content)
+		return nil
+	}
+	if err := os.WriteFile(path, content, perm); err != nil {
+		return fmt.Errorf("failed to write %s file: %w", path, err)
+	}
+	return nil
+}
@@ -3,125 +3,95 @@ package utils
 import (
 	"bufio"
 	"fmt"
-	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"syscall"
-	"time"
 
 	"github.com/spf13/viper"
 	"golang.org/x/term"
 )
 
-func GetCommand() string {
-	command := ""
-
-	_, err := exec.LookPath("kubectl")
-	if err == nil {
-		if err = exec.Command("kubectl", "get", "pod").Run(); err != nil {
-			log.Print("kubectl not configured to connect to a cluster, ignoring")
-		} else {
-			command = "kubectl"
-		}
-	}
-
-	if _, err = exec.LookPath("podman"); err == nil {
-		command = "podman"
-	}
-
-	if command == "" {
-		log.Fatal("Neither podman nor kubectl are available")
-	}
-	return command
-}
-
-func GetPodName(fail bool) (string, string) {
-	command := GetCommand()
-	pod := "uyuni-server"
-
-	switch command {
-	case "podman":
-		if out, _ := exec.Command("podman", "ps", "-q", "-f", "name="+pod).Output(); len(out) == 0 {
-			if fail {
-				log.Fatalf("Container %s is not running on podman", pod)
-			}
-		}
-	case "kubectl":
-		podCmd := exec.Command("kubectl", "get", "pod", "-lapp=uyuni", "-o=jsonpath={.items[0].metadata.name}")
-		podName, err := podCmd.Output()
-		if err == nil {
-			pod = string(podName[:])
-		}
-	}
-	return command, pod
-}
-
-// WaitForServer waits at most 60s for multi-user systemd target to be reached.
-func WaitForServer() {
-	// Wait for the system to be up
-	for i := 0; i < 60; i++ {
-		cmd, podName := GetPodName(false)
-		args := []string{"exec", podName}
-		if cmd == "kubectl" {
-			args = append(args, "--")
-		}
-		args = append(args, "systemctl", "is-active", "-q", "multi-user.target")
-		testCmd := exec.Command(cmd, args...)
-		testCmd.Run()
-		log.Printf("Ran %s %s: %d\n", cmd, strings.Join(args, " "), testCmd.ProcessState.ExitCode())
-		if testCmd.ProcessState.ExitCode() == 0 {
-			return
-		}
-		time.Sleep(1 * time.Second)
-	}
-	log.Fatalf("Server didn't start within 60s")
-}
-
-func RunCmd(command string, args []string, errMessage string, verbose bool) {
-	if verbose {
-		fmt.Printf("> Running: %s %s\n", command, strings.Join(args, " "))
-	}
+// RunCmd runs a command and returns a CmdError with errMessage and the command output if it fails.
+func RunCmd(command string, args []string, errMessage string) error {
 	cmd := exec.Command(command, args...)
-	if out, err := cmd.CombinedOutput(); err != nil {
-		log.Fatalf("%s:\n  %s\n", errMessage, strings.ReplaceAll(string(out[:]), "\n", "\n  "))
+	if out, err := GetRunner().CombinedOutput(cmd); err != nil {
+		return NewCmdError(errMessage, cmd, out, err)
 	}
+	return nil
 }
 
 const PROMPT_END = ": "
 
-func AskPasswordIfMissing(viper *viper.Viper, key string, prompt string) {
+func AskPasswordIfMissing(viper *viper.Viper, key string, prompt string) error {
 	value := viper.GetString(key)
 	if value == "" {
 		fmt.Print(prompt + PROMPT_END)
 		bytePassword, err := term.ReadPassword(int(syscall.Stdin))
 		if err != nil {
-			log.Fatalf("Failed to read password: %s\n", err)
+			return fmt.Errorf("failed to read password: %w", err)
 		}
 		viper.Set(key, string(bytePassword))
 		fmt.Println()
 	}
+	return nil
 }
 
-func AskIfMissing(viper *viper.Viper, key string, prompt string) {
+func AskIfMissing(viper *viper.Viper, key string, prompt string) error {
 	value := viper.GetString(key)
 	if value == "" {
 		fmt.Print(prompt + PROMPT_END)
 		reader := bufio.NewReader(os.Stdin)
 		value, err := reader.ReadString('\n')
 		if err != nil {
-			log.Fatalf("Failed to read input: %s\n", err)
+			return fmt.Errorf("failed to read input: %w", err)
 		}
 		viper.Set(key, value)
 		fmt.Println()
 	}
+	return nil
 }
 
 // Get the timezone set on the machine running the tool
-func GetLocalTimezone() string {
-	out, err := exec.Command("timedatectl", "show", "--value", "-p", "Timezone").Output()
+func GetLocalTimezone() (string, error) {
+	cmd := exec.Command("timedatectl", "show", "--value", "-p", "Timezone")
+	out, err := GetQueryRunner().Output(cmd)
+	if err != nil {
+		return "", NewCmdError("failed to get the local timezone", cmd, nil, err)
+	}
+	return string(out), nil
+}
+
+// AskConfirmation asks a yes / no question and returns true if the answer is yes.
+func AskConfirmation(prompt string) (bool, error) {
+	fmt.Print(prompt + " [y/N]" + PROMPT_END)
+	reader := bufio.NewReader(os.Stdin)
+	answer, err := reader.ReadString('\n')
 	if err != nil {
-		log.Fatalf("Failed to run timedatectl show --value -p Timezone: %s\n", err)
+		return false, fmt.Errorf("failed to read input: %w", err)
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+// systemStateDir is the folder where the tools keep their state when running as root.
+const systemStateDir = "/var/lib/uyuni-tools"
+
+// GetDefaultStateDir returns the folder where the tools keep a kind of state, like the migrations, if none is configured.
+// Users other than root can't write in /var/lib: their states are in their XDG state folder.
+// The flag is the one to suggest if the folder can't be found.
+func GetDefaultStateDir(name string, flag string) (string, error) {
+	if os.Geteuid() == 0 {
+		return filepath.Join(systemStateDir, name), nil
+	}
+	stateHome := os.Getenv("XDG_STATE_HOME")
+	if stateHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", &ConfigError{Message: fmt.Sprintf("failed to find the home folder, use %s to set the %s folder", flag, name), Err: err}
+		}
+		stateHome = filepath.Join(home, ".local", "state")
 	}
-	return string(out)
+	return filepath.Join(stateHome, "uyuni-tools", name), nil
 }
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ReleaseCommand is the shell command printing the server release in the container.
+const ReleaseCommand = "cat /etc/uyuni-release /etc/susemanager-release 2>/dev/null"
+
+// SchemaUpgradeCommand is the shell command upgrading the database schema in the container
+// and restarting the services using it.
+const SchemaUpgradeCommand = "spacewalk-schema-upgrade -y && spacewalk-service restart"
+
+var versionRegexp = regexp.MustCompile(`[0-9]+(\.[0-9]+)+`)
+
+// ParseServerVersion extracts the version from the server release file content.
+// An empty string is returned if no version can be found.
+func ParseServerVersion(release string) string {
+	return versionRegexp.FindString(release)
+}
+
+// CompareVersions returns -1, 0 or 1 if version a is lower, equal or greater than b.
+// Only the dot-separated numbers are compared.
+func CompareVersions(a string, b string) int {
+	aParts := strings.Split(a, ".")
+	bParts := strings.Split(b, ".")
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		aValue, bValue := 0, 0
+		if i < len(aParts) {
+			aValue, _ = strconv.Atoi(aParts[i])
+		}
+		if i < len(bParts) {
+			bValue, _ = strconv.Atoi(bParts[i])
+		}
+		if aValue < bValue {
+			return -1
+		} else if aValue > bValue {
+			return 1
+		}
+	}
+	return 0
+}
+
+// CheckUpgradeVersions returns an error if the release of the new image is older than the running one.
+// The parameters are the content of the release files of the running server and of the new image.
+func CheckUpgradeVersions(currentRelease string, newRelease string) error {
+	current := ParseServerVersion(currentRelease)
+	next := ParseServerVersion(newRelease)
+	if current == "" {
+		return fmt.Errorf("failed to find the version of the running server in: %s", currentRelease)
+	}
+	if next == "" {
+		return fmt.Errorf("failed to find the server version in the new image: %s", newRelease)
+	}
+	if CompareVersions(next, current) < 0 {
+		return fmt.Errorf("cannot upgrade from version %s to older version %s", current, next)
+	}
+	log.Printf("Upgrading server from version %s to %s\n", current, next)
+	return nil
+}
@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+)
+
+// Delays between the checks of WaitFor: the first one is short, then it doubles up to the maximum.
+const (
+	waitInitialDelay = 500 * time.Millisecond
+	waitMaxDelay     = 10 * time.Second
+)
+
+// waitProgressDelay is how often the progress of a wait is reported.
+const waitProgressDelay = 15 * time.Second
+
+// ServerServices are the main systemd services of the server container.
+var ServerServices = []string{
+	"tomcat.service",
+	"taskomatic.service",
+	"salt-master.service",
+	"postgresql.service",
+	"apache2.service",
+	"cobblerd.service",
+}
+
+// WaitCheck tells whether the state waited for is reached.
+// It returns an error for the terminal failures no waiting can fix, like a crashing container.
+type WaitCheck func() (bool, error)
+
+// WaitFor runs check with exponentially growing delays until it returns true or an error,
+// or until timeout is reached.
+// The description tells what is waited for in the progress messages, like "the server to start".
+// Nothing is waited for with --dry-run as the commands changing the state haven't been run.
+func WaitFor(description string, timeout time.Duration, check WaitCheck) error {
+	if IsDryRun() {
+		log.Printf("Would wait for %s\n", description)
+		return nil
+	}
+
+	stop := StartWaitProgress(description)
+	defer stop()
+
+	deadline := time.Now().Add(timeout)
+	delay := waitInitialDelay
+	for {
+		done, err := check()
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return &TimeoutError{Description: description, Timeout: timeout}
+		}
+		if delay > remaining {
+			delay = remaining
+		}
+		time.Sleep(delay)
+		delay *= 2
+		if delay > waitMaxDelay {
+			delay = waitMaxDelay
+		}
+	}
+}
+
+// StartWaitProgress logs that description is waited for and reminds it regularly
+// until the returned function is called.
+func StartWaitProgress(description string) func() {
+	log.Printf("Waiting for %s\n", description)
+	start := time.Now()
+	ticker := time.NewTicker(waitProgressDelay)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				log.Printf("Still waiting for %s (%s elapsed)\n", description, time.Since(start).Round(time.Second))
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
+
+// WaitForServer waits for the multi-user systemd target to be reached in the server container.
+// If checkFailed is true, a failed server service is reported as soon as it is seen.
+// It needs to be false for a server not set up yet as its services fail until mgr-setup configures them.
+func WaitForServer(backend types.Backend, timeout time.Duration, checkFailed bool) error {
+	return WaitFor("the server services to start", timeout, func() (bool, error) {
+		if checkFailed {
+			failed, err := getFailedServices(backend)
+			if err != nil {
+				// The container may not be started yet
+				return false, nil
+			}
+			if len(failed) > 0 {
+				return false, fmt.Errorf("failed server services: %s, run systemctl status in the server for details",
+					strings.Join(failed, ", "))
+			}
+		}
+
+		cmd, err := backend.Command("systemctl", "is-active", "-q", "multi-user.target")
+		if err != nil {
+			return false, nil
+		}
+		return GetQueryRunner().Run(cmd) == nil, nil
+	})
+}
+
+// getFailedServices returns the server services systemd gave up restarting.
+func getFailedServices(backend types.Backend) ([]string, error) {
+	args := append([]string{"systemctl", "list-units", "--state=failed", "--no-legend", "--plain"}, ServerServices...)
+	cmd, err := backend.Command(args...)
+	if err != nil {
+		return nil, err
+	}
+	out, err := GetQueryRunner().Output(cmd)
+	if err != nil {
+		return nil, err
+	}
+	failed := []string{}
+	for _, line := range strings.Split(string(out), "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			failed = append(failed, fields[0])
+		}
+	}
+	return failed, nil
+}
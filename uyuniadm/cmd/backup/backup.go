@@ -0,0 +1,45 @@
+package backup
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/uyuni-project/uyuni-tools/shared/backend"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+)
+
+type flagpole struct {
+	Output string
+	TmpDir string
+}
+
+func NewCommand(globalFlags *types.GlobalFlags) *cobra.Command {
+	flags := &flagpole{}
+
+	backupCmd := &cobra.Command{
+		Use:   "backup",
+		Short: "backup the server data to an archive",
+		Long: `Backup the server data to an archive
+
+The services of the server are stopped during the backup, only the database is kept running.
+The archive contains:
+  * a dump of the PostgreSQL database and its configuration files,
+  * a tar archive of each of the other server volumes,
+  * a manifest.json file with the image, version and FQDN of the server and the checksums of the other files.
+
+The archive is compressed with zstd if the output path ends with .zst or gzip if it ends with .gz.
+`,
+		Args: cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, err := backend.Get(globalFlags)
+			if err != nil {
+				return err
+			}
+			return runBackup(globalFlags, b, flags)
+		},
+	}
+
+	backupCmd.Flags().StringVarP(&flags.Output, "output", "o", "", "Path to the archive to create, for example uyuni-backup.tar.zst")
+	backupCmd.Flags().StringVar(&flags.TmpDir, "tmp-dir", "", "Folder where to store the temporary files. Defaults to the output folder")
+	backupCmd.MarkFlagRequired("output")
+
+	return backupCmd
+}
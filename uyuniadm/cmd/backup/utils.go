@@ -0,0 +1,169 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// The database volume is saved as a dump instead of a copy of the files.
+const databaseVolume = "var-pgsql"
+
+func runBackup(globalFlags *types.GlobalFlags, backend types.Backend, flags *flagpole) error {
+	tmpDir := flags.TmpDir
+	if tmpDir == "" {
+		tmpDir = filepath.Dir(flags.Output)
+	}
+	workDir, err := os.MkdirTemp(tmpDir, "uyuniadm-backup-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	manifest, err := getManifest(backend)
+	if err != nil {
+		return err
+	}
+
+	// With --dry-run, the archive of the empty outputs goes away with the temporary directory
+	output := flags.Output
+	if utils.IsDryRun() {
+		output = filepath.Join(workDir, filepath.Base(flags.Output))
+	}
+	archive, err := utils.CreateArchive(output)
+	if err != nil {
+		return err
+	}
+
+	// Don't leave an incomplete archive: it would prevent running the backup again
+	if err := writeArchive(globalFlags, backend, archive, workDir, manifest); err != nil {
+		archive.Close()
+		os.Remove(output)
+		return err
+	}
+	if err = archive.Close(); err != nil {
+		os.Remove(output)
+		return fmt.Errorf("failed to write %s archive: %w", output, err)
+	}
+	if utils.IsDryRun() {
+		log.Printf("Would back up the server to %s\n", flags.Output)
+		return nil
+	}
+	log.Printf("Server backed up to %s\n", flags.Output)
+	return nil
+}
+
+// writeArchive stops the server services to add the database, the volumes and the manifest to the archive.
+func writeArchive(globalFlags *types.GlobalFlags, backend types.Backend, archive *utils.ArchiveWriter,
+	workDir string, manifest *types.BackupManifest) error {
+	log.Println("Stopping the server services")
+	if err := utils.RunServerCmd(backend, "spacewalk-service stop", nil, "failed to stop the services"); err != nil {
+		return err
+	}
+
+	saveErr := saveEntries(globalFlags, backend, archive, workDir, manifest)
+
+	// Restart the services even if the backup failed
+	log.Println("Starting the server services")
+	if err := utils.RunServerCmd(backend, "spacewalk-service start", nil, "failed to start the services"); err != nil {
+		if saveErr != nil {
+			return fmt.Errorf("%w\n%s", saveErr, err)
+		}
+		return err
+	}
+	if saveErr != nil {
+		return saveErr
+	}
+
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to generate the backup manifest: %w", err)
+	}
+	return archive.AddContent(types.BackupManifestName, content)
+}
+
+// getManifest returns a manifest describing the running server, without any saved entry.
+func getManifest(backend types.Backend) (*types.BackupManifest, error) {
+	image, err := backend.GetImage()
+	if err != nil {
+		return nil, err
+	}
+	release, err := utils.GetServerCmdOutput(backend, utils.ReleaseCommand)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the server version: %w", err)
+	}
+	fqdn, err := utils.GetServerCmdOutput(backend, utils.FqdnCommand)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the server FQDN: %w", err)
+	}
+	timezone, err := utils.GetServerCmdOutput(backend, "echo ${TZ:-Etc/UTC}")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the server timezone: %w", err)
+	}
+
+	return &types.BackupManifest{
+		Image:    image,
+		Version:  utils.ParseServerVersion(release),
+		Fqdn:     fqdn,
+		Timezone: timezone,
+		Date:     time.Now().UTC().Format(time.RFC3339),
+		Volumes:  map[string]types.BackupEntry{},
+	}, nil
+}
+
+// saveEntries adds the database and the volumes to the archive and records them in the manifest.
+func saveEntries(globalFlags *types.GlobalFlags, backend types.Backend, archive *utils.ArchiveWriter,
+	workDir string, manifest *types.BackupManifest) error {
+	var err error
+	log.Println("Saving the database")
+	manifest.Database, err = saveEntry(globalFlags, backend, archive, workDir, "database.sql", "",
+		"su - postgres -c pg_dumpall")
+	if err != nil {
+		return err
+	}
+	manifest.DatabaseConfig, err = saveEntry(globalFlags, backend, archive, workDir, "database-config.tar", "",
+		"cd /var/lib/pgsql/data && tar -cf - *.conf")
+	if err != nil {
+		return err
+	}
+
+	for name, path := range utils.VOLUMES {
+		if name == databaseVolume {
+			continue
+		}
+		log.Printf("Saving volume %s\n", name)
+		manifest.Volumes[name], err = saveEntry(globalFlags, backend, archive, workDir, "volumes/"+name+".tar", path,
+			"tar -C "+path+" -cf - .")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveEntry runs a shell command in the server container and adds its output to the archive as name.
+func saveEntry(globalFlags *types.GlobalFlags, backend types.Backend, archive *utils.ArchiveWriter,
+	workDir string, name string, path string, command string) (types.BackupEntry, error) {
+	tmpPath := filepath.Join(workDir, filepath.Base(name))
+	defer os.Remove(tmpPath)
+
+	cmd, err := backend.Command("sh", "-c", command)
+	if err != nil {
+		return types.BackupEntry{}, err
+	}
+	checksum, err := utils.SaveCommandOutput(cmd, tmpPath)
+	if err != nil {
+		return types.BackupEntry{}, err
+	}
+	if err := archive.AddFile(name, tmpPath); err != nil {
+		return types.BackupEntry{}, err
+	}
+
+	return types.BackupEntry{File: name, Path: path, Checksum: checksum}, nil
+}
@@ -0,0 +1,140 @@
+package cert
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/uyuni-project/uyuni-tools/shared/backend"
+	"github.com/uyuni-project/uyuni-tools/shared/kubernetes"
+	"github.com/uyuni-project/uyuni-tools/shared/podman"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+func NewCommand(globalFlags *types.GlobalFlags) *cobra.Command {
+	certCmd := &cobra.Command{
+		Use:   "cert",
+		Short: "manage the server SSL certificates",
+		Long:  "Manage the server SSL certificates",
+	}
+
+	renewCmd := &cobra.Command{
+		Use:   "renew",
+		Short: "renew the server SSL certificate",
+		Long: `Renew the server SSL certificate
+
+A new server certificate is generated and signed by the existing CA:
+  * on podman using rhn-ssl-tool in the server container, the CA password is required,
+  * on kubernetes by cert-manager.
+
+To use a certificate issued by another authority, pass the --cert-ca, --cert-server-cert and --cert-server-key files.
+They are validated against the server FQDN and the CNAMEs before being deployed.
+
+The CA certificate is then updated in the server trust anchors and pub folder and the services are restarted.
+`,
+		Args: cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return renew(cmd, globalFlags, false)
+		},
+	}
+
+	rotateCaCmd := &cobra.Command{
+		Use:   "rotate-ca",
+		Short: "generate a new CA and server SSL certificate",
+		Long: `Generate a new CA and server SSL certificate
+
+The CA and the server certificate are regenerated like the renew command does for the server certificate.
+
+Note that the clients need to trust the new CA certificate to connect to the server.
+`,
+		Args: cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return renew(cmd, globalFlags, true)
+		},
+	}
+
+	showCmd := &cobra.Command{
+		Use:   "show",
+		Short: "show the server SSL certificates",
+		Long:  "Show the subject, issuer, names and validity of the CA and server SSL certificates",
+		Args:  cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			viper, err := utils.ReadConfig(globalFlags.ConfigPath, "admconfig", cmd)
+			if err != nil {
+				return err
+			}
+			b, err := backend.Get(globalFlags)
+			if err != nil {
+				return err
+			}
+			ca, server, err := b.GetCertificates(viper)
+			if err != nil {
+				return err
+			}
+			if err := printCertificates("CA", ca); err != nil {
+				return err
+			}
+			return printCertificates("Server", server)
+		},
+	}
+
+	syncCaCmd := &cobra.Command{
+		Use:   "sync-ca",
+		Short: "copy the CA certificate to the uyuni-ca configmap on kubernetes",
+		Long: `Copy the CA certificate from the uyuni-ca secret to the uyuni-ca configmap on kubernetes
+
+This is only needed on clusters without trust-manager, after the CA certificate has been changed.
+When trust-manager is installed, a Bundle keeps the configmap in sync.
+`,
+		Args: cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			viper, err := utils.ReadConfig(globalFlags.ConfigPath, "admconfig", cmd)
+			if err != nil {
+				return err
+			}
+			b, err := backend.Get(globalFlags)
+			if err != nil {
+				return err
+			}
+			if b.Name() != kubernetes.Name {
+				return fmt.Errorf("the CA configmap only exists on kubernetes, not on %s", b.Name())
+			}
+			return kubernetes.SyncCaConfigMap(viper.GetString("helm.uyuni.namespace"), globalFlags.Verbose)
+		},
+	}
+
+	certCmd.AddCommand(renewCmd)
+	certCmd.AddCommand(rotateCaCmd)
+	certCmd.AddCommand(showCmd)
+	certCmd.AddCommand(syncCaCmd)
+
+	return certCmd
+}
+
+func renew(cmd *cobra.Command, globalFlags *types.GlobalFlags, rotateCa bool) error {
+	viper, err := utils.ReadConfig(globalFlags.ConfigPath, "admconfig", cmd)
+	if err != nil {
+		return err
+	}
+	b, err := backend.Get(globalFlags)
+	if err != nil {
+		return err
+	}
+	if err := checkParameters(cmd, viper, b); err != nil {
+		return err
+	}
+	fqdn, err := utils.GetServerCmdOutput(b, utils.FqdnCommand)
+	if err != nil {
+		return fmt.Errorf("failed to get the server FQDN: %w", err)
+	}
+	return b.RenewCertificates(viper, globalFlags, fqdn, rotateCa)
+}
+
+func checkParameters(cmd *cobra.Command, viper *viper.Viper, backend types.Backend) error {
+	// The CA password is only needed to sign the certificates generated in the podman container
+	if viper.GetString("cert.server.cert") == "" && backend.Name() == podman.Name {
+		return utils.AskPasswordIfMissing(viper, "cert.password", cmd.Flag("cert-password").Usage)
+	}
+	return nil
+}
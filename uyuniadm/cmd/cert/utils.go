@@ -0,0 +1,42 @@
+package cert
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// printCertificates shows the main fields of the certificates in PEM data.
+func printCertificates(title string, content []byte) error {
+	certificates, err := utils.ParseCertificates(content)
+	if err != nil {
+		return fmt.Errorf("invalid %s certificate: %w", strings.ToLower(title), err)
+	}
+	if len(certificates) == 0 {
+		return fmt.Errorf("no %s certificate found", strings.ToLower(title))
+	}
+
+	for i, certificate := range certificates {
+		if i == 0 {
+			fmt.Printf("%s certificate:\n", title)
+		} else {
+			fmt.Printf("%s intermediate certificate:\n", title)
+		}
+		fmt.Printf("  Subject:    %s\n", certificate.Subject.String())
+		fmt.Printf("  Issuer:     %s\n", certificate.Issuer.String())
+		if len(certificate.DNSNames) > 0 {
+			fmt.Printf("  Names:      %s\n", strings.Join(certificate.DNSNames, ", "))
+		}
+		fmt.Printf("  Not before: %s\n", certificate.NotBefore.Format(time.RFC3339))
+		fmt.Printf("  Not after:  %s", certificate.NotAfter.Format(time.RFC3339))
+		if remaining := time.Until(certificate.NotAfter); remaining < 0 {
+			fmt.Println(" (expired)")
+		} else {
+			fmt.Printf(" (%d days left)\n", int(remaining.Hours()/24))
+		}
+		fmt.Println()
+	}
+	return nil
+}
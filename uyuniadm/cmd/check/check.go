@@ -0,0 +1,63 @@
+package check
+
+import (
+	"errors"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/uyuni-project/uyuni-tools/shared/backend"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+type flagpole struct {
+	Output string
+}
+
+func NewCommand(globalFlags *types.GlobalFlags) *cobra.Command {
+	flags := &flagpole{}
+
+	checkCmd := &cobra.Command{
+		Use:   "check [fqdn]",
+		Short: "check the machine is ready for an install or a migration",
+		Long: `Check the machine is ready for an install or a migration
+
+The checks depend on the backend:
+  * on podman: the required binaries, the free space for each volume, the ports to expose,
+    the cgroup version and the SELinux state,
+  * on kubernetes: the required binaries, the connection to the cluster and the permissions.
+
+If a FQDN is passed, its forward and reverse DNS resolution are also checked.
+The command exits with an error if one of the checks failed.
+
+The install and migrate commands run the same checks before starting.
+`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			viper, err := utils.ReadConfig(globalFlags.ConfigPath, "admconfig", cmd)
+			if err != nil {
+				return err
+			}
+			fqdn := ""
+			if len(args) > 0 {
+				fqdn = args[0]
+			}
+			b, err := backend.Get(globalFlags)
+			if err != nil {
+				return err
+			}
+			results := b.Check(viper, fqdn)
+			if err := utils.PrintCheckResults(results, flags.Output, os.Stdout); err != nil {
+				return err
+			}
+			if utils.HasFailedChecks(results) {
+				return errors.New("some checks failed")
+			}
+			return nil
+		},
+	}
+
+	checkCmd.Flags().StringVarP(&flags.Output, "output", "o", "table", "Output format: table or json")
+
+	return checkCmd
+}
@@ -1,11 +1,22 @@
 package cmd
 
 import (
+	"strings"
+	"time"
+
 	"github.com/spf13/cobra"
+	"github.com/uyuni-project/uyuni-tools/shared/backend"
+	"github.com/uyuni-project/uyuni-tools/shared/kubernetes"
 	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+	"github.com/uyuni-project/uyuni-tools/uyuniadm/cmd/backup"
+	"github.com/uyuni-project/uyuni-tools/uyuniadm/cmd/cert"
+	"github.com/uyuni-project/uyuni-tools/uyuniadm/cmd/check"
 	"github.com/uyuni-project/uyuni-tools/uyuniadm/cmd/install"
 	"github.com/uyuni-project/uyuni-tools/uyuniadm/cmd/migrate"
+	"github.com/uyuni-project/uyuni-tools/uyuniadm/cmd/restore"
 	"github.com/uyuni-project/uyuni-tools/uyuniadm/cmd/uninstall"
+	"github.com/uyuni-project/uyuni-tools/uyuniadm/cmd/upgrade"
 )
 
 // NewCommand returns a new cobra.Command implementing the root command for kinder
@@ -16,28 +27,95 @@ func NewUyuniadmCommand() *cobra.Command {
 		Short:   "Uyuni administration tool",
 		Long:    "Uyuni administration tool used to help user administer uyuni servers on k8s and podman",
 		Version: "0.0.1",
+		// The errors are printed by main with the matching exit code
+		SilenceUsage:  true,
+		SilenceErrors: true,
 	}
 
 	rootCmd.PersistentFlags().BoolVarP(&globalFlags.Verbose, "verbose", "v", false, "verbose output")
+	rootCmd.PersistentFlags().BoolVarP(&globalFlags.DryRun, "dry-run", "n", false, "Only print the commands which would be run")
 	rootCmd.PersistentFlags().StringVarP(&globalFlags.ConfigPath, "config", "c", "", "configuration file path")
+	rootCmd.PersistentFlags().StringVar(&globalFlags.Backend, "backend", backend.Auto,
+		"tool to use to manage the server: "+strings.Join(backend.Names(), ", ")+" or "+backend.Auto)
+	rootCmd.PersistentFlags().String("helm-uyuni-namespace", kubernetes.DefaultNamespace, "Kubernetes namespace of uyuni")
+	rootCmd.PersistentFlags().String("instance", "", "Name of the server instance on a podman host running several servers")
+	rootCmd.PersistentFlags().Bool("rootless", false, "Manage a server running in rootless podman of the current user")
+	rootCmd.PersistentFlags().String("kube-context", "", "Kubeconfig context of the cluster to deploy to, the current context by default")
+	rootCmd.PersistentFlags().Int("timeout-image-pull", int(types.DefaultImagePullTimeout.Seconds()),
+		"Seconds to wait for the images to be pulled")
+	rootCmd.PersistentFlags().Int("timeout-deployment", int(types.DefaultDeploymentTimeout.Seconds()),
+		"Seconds to wait for a kubernetes pod to be created and to turn ready, not counting the image pull")
+	rootCmd.PersistentFlags().Int("timeout-server-start", int(types.DefaultServerStartTimeout.Seconds()),
+		"Seconds to wait for the services to start in the server container")
+	rootCmd.PersistentFlags().Int("timeout-certificates", int(types.DefaultCertificatesTimeout.Seconds()),
+		"Seconds to wait for the certificates to be issued on kubernetes")
+	rootCmd.PersistentFlags().Int("timeout-migration", int(types.DefaultMigrationTimeout.Seconds()),
+		"Seconds to wait for the migration job to synchronize the source server data on kubernetes")
+
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		// The backend can also be set in the configuration file or UYUNI_BACKEND environment variable
+		viper, err := utils.ReadConfig(globalFlags.ConfigPath, "admconfig", cmd)
+		if err != nil {
+			return err
+		}
+		globalFlags.Backend = viper.GetString("backend")
+		globalFlags.Namespace = viper.GetString("helm.uyuni.namespace")
+		globalFlags.Instance = viper.GetString("instance")
+		globalFlags.Rootless = viper.GetBool("rootless")
+		globalFlags.KubeContext = viper.GetString("kube.context")
+		globalFlags.Timeouts = types.Timeouts{
+			ImagePull:    time.Duration(viper.GetInt("timeout.image.pull")) * time.Second,
+			Deployment:   time.Duration(viper.GetInt("timeout.deployment")) * time.Second,
+			ServerStart:  time.Duration(viper.GetInt("timeout.server.start")) * time.Second,
+			Certificates: time.Duration(viper.GetInt("timeout.certificates")) * time.Second,
+			Migration:    time.Duration(viper.GetInt("timeout.migration")) * time.Second,
+		}
+		utils.SetRunner(utils.NewRunner(globalFlags))
+		return nil
+	}
 
 	migrateCmd := migrate.NewCommand(globalFlags)
 	addCommonFlags(migrateCmd)
+	addCertFlags(migrateCmd)
 	rootCmd.AddCommand(migrateCmd)
 
 	installCmd := install.NewCommand(globalFlags)
 	addCommonFlags(installCmd)
+	addCertFlags(installCmd)
 	rootCmd.AddCommand(installCmd)
 
+	upgradeCmd := upgrade.NewCommand(globalFlags)
+	addCommonFlags(upgradeCmd)
+	rootCmd.AddCommand(upgradeCmd)
+
 	rootCmd.AddCommand(uninstall.NewCommand(globalFlags))
+	rootCmd.AddCommand(backup.NewCommand(globalFlags))
+
+	checkCmd := check.NewCommand(globalFlags)
+	addCommonFlags(checkCmd)
+	rootCmd.AddCommand(checkCmd)
+
+	restoreCmd := restore.NewCommand(globalFlags)
+	addCommonFlags(restoreCmd)
+	addCertFlags(restoreCmd)
+	rootCmd.AddCommand(restoreCmd)
+
+	certCmd := cert.NewCommand(globalFlags)
+	for _, subCmd := range certCmd.Commands() {
+		addCommonFlags(subCmd)
+		if subCmd.Name() == "renew" || subCmd.Name() == "rotate-ca" {
+			addCertFlags(subCmd)
+		}
+	}
+	rootCmd.AddCommand(certCmd)
 
 	return rootCmd
 }
 
 func addCommonFlags(cmd *cobra.Command) {
 	cmd.Flags().StringArray("podman-arg", []string{}, "Extra arguments to pass to podman")
+	cmd.Flags().StringArray("podman-port", []string{}, "Publish a server port on another host port, like 8443:443")
 
-	cmd.Flags().String("helm-uyuni-namespace", "default", "Kubernetes namespace where to install uyuni")
 	cmd.Flags().String("helm-uyuni-chart", "oci://registry.opensuse.org/uyuni/server", "URL to the uyuni helm chart")
 	cmd.Flags().String("helm-uyuni-version", "", "Version of the uyuni helm chart")
 	cmd.Flags().String("helm-uyuni-values", "", "Path to a values YAML file to use for Uyuni helm install")
@@ -45,4 +123,30 @@ func addCommonFlags(cmd *cobra.Command) {
 	cmd.Flags().String("helm-certmanager-chart", "", "URL to the cert-manager helm chart. To be used for offline installations")
 	cmd.Flags().String("helm-certmanager-version", "", "Version of the cert-manager helm chart")
 	cmd.Flags().String("helm-certmanager-values", "", "Path to a values YAML file to use for cert-manager helm install")
+	cmd.Flags().Bool("helm-trustmanager-install", false, "Install trust-manager to keep the CA configmap in sync if not already available")
+	cmd.Flags().String("helm-trustmanager-namespace", "cert-manager", "Kubernetes namespace where to install trust-manager")
+	cmd.Flags().String("helm-trustmanager-chart", "", "URL to the trust-manager helm chart. To be used for offline installations")
+	cmd.Flags().String("helm-trustmanager-version", "", "Version of the trust-manager helm chart")
+	cmd.Flags().String("helm-trustmanager-values", "", "Path to a values YAML file to use for trust-manager helm install")
+}
+
+func addCertFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("cert-useexisting", false, "Use existing SSL certificate")
+	cmd.Flags().String("cert-ca", "", "Path to the root CA certificate, when using existing certificates")
+	cmd.Flags().StringArray("cert-intermediate", []string{}, "Path to an intermediate CA certificate, when using existing certificates")
+	cmd.Flags().String("cert-server-cert", "", "Path to the server certificate, when using existing certificates")
+	cmd.Flags().String("cert-server-key", "", "Path to the server key, when using existing certificates")
+	cmd.Flags().StringArray("cert-cname", []string{}, "SSL certificate cnames separated by commas")
+	cmd.Flags().String("cert-country", "DE", "SSL certificate country")
+	cmd.Flags().String("cert-state", "Bayern", "SSL certificate state")
+	cmd.Flags().String("cert-city", "Nuernberg", "SSL certificate city")
+	cmd.Flags().String("cert-org", "SUSE", "SSL certificate organization")
+	cmd.Flags().String("cert-ou", "SUSE", "SSL certificate organization unit")
+	cmd.Flags().String("cert-password", "", "Password for the CA certificate to generate")
+	cmd.Flags().String("cert-email", "ca-admin@example.com", "SSL certificate E-Mail")
+	cmd.Flags().String("cert-issuer-name", "", "Name of an existing cert-manager issuer to use instead of the self-signed CA on kubernetes")
+	cmd.Flags().String("cert-issuer-kind", "Issuer", "Kind of the cert-manager issuer: Issuer or ClusterIssuer")
+	cmd.Flags().String("cert-key-algorithm", "ECDSA", "Private key algorithm of the certificates issued by cert-manager: RSA, ECDSA or Ed25519")
+	cmd.Flags().Int("cert-key-size", 0, "Private key size of the certificates issued by cert-manager: 2048 to 8192 for RSA, 256, 384 or 521 for ECDSA. "+
+		"Defaults to 2048 for RSA and 256 for ECDSA, ignored for Ed25519")
 }
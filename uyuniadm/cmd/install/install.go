@@ -3,6 +3,8 @@ package install
 import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"github.com/uyuni-project/uyuni-tools/shared/backend"
+	"github.com/uyuni-project/uyuni-tools/shared/podman"
 	"github.com/uyuni-project/uyuni-tools/shared/types"
 	"github.com/uyuni-project/uyuni-tools/shared/utils"
 )
@@ -36,22 +38,35 @@ When installing on kubernetes, the helm values file will be overridden with the
 NOTE: for now installing on a remote cluster or podman is not supported!
 `,
 		Args: cobra.ExactArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
-			viper := utils.ReadConfig(globalFlags.ConfigPath, "admconfig", cmd)
-			command := utils.GetCommand()
-			checkParameters(cmd, viper, flags, command)
-			switch command {
-			case "podman":
-				installForPodman(viper, globalFlags, cmd, args)
-			case "kubectl":
-				installForKubernetes(viper, globalFlags, cmd, args)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			viper, err := utils.ReadConfig(globalFlags.ConfigPath, "admconfig", cmd)
+			if err != nil {
+				return err
 			}
+			b, err := backend.Get(globalFlags)
+			if err != nil {
+				return err
+			}
+			if !viper.GetBool("skip.checks") {
+				if err := utils.RunPreflightChecks(b.Check(viper, args[0])); err != nil {
+					return err
+				}
+			}
+			if err := checkParameters(cmd, viper, flags, b); err != nil {
+				return err
+			}
+			env, err := b.Install(viper, globalFlags, args[0])
+			if err != nil {
+				return err
+			}
+			return runSetup(viper, globalFlags, b, args[0], env)
 		},
 	}
 
 	installCmd.Flags().String("image", "registry.opensuse.org/uyuni/server", "Image")
 	installCmd.Flags().String("tag", "latest", "Tag Image")
 
+	installCmd.Flags().Bool("skip-checks", false, "Do not run the pre-flight checks")
 	installCmd.Flags().String("tz", "Etc/UTC", "Time zone to set on the server. Defaults to the host timezone")
 	installCmd.Flags().String("email", "admin@example.com", "Administrator e-mail")
 	installCmd.Flags().String("emailfrom", "admin@example.com", "E-Mail sending the notifications")
@@ -74,40 +89,41 @@ NOTE: for now installing on a remote cluster or podman is not supported!
 	installCmd.Flags().String("reportdb-user", "pythia_susemanager", "Report Database username")
 	installCmd.Flags().String("reportdb-password", "", "Report database password. Randomly generated by default")
 
-	installCmd.Flags().Bool("cert-useexisting", false, "Use existing SSL certificate")
-	installCmd.Flags().StringArray("cert-cname", []string{}, "SSL certificate cnames separated by commas")
-	installCmd.Flags().String("cert-country", "DE", "SSL certificate country")
-	installCmd.Flags().String("cert-state", "Bayern", "SSL certificate state")
-	installCmd.Flags().String("cert-city", "Nuernberg", "SSL certificate city")
-	installCmd.Flags().String("cert-org", "SUSE", "SSL certificate organization")
-	installCmd.Flags().String("cert-ou", "SUSE", "SSL certificate organization unit")
-	installCmd.Flags().String("cert-password", "", "Password for the CA certificate to generate")
-	installCmd.Flags().String("cert-email", "ca-admin@example.com", "SSL certificate E-Mail")
-
 	installCmd.Flags().String("scc-user", "", "SUSE Customer Center username")
 	installCmd.Flags().String("scc-password", "", "SUSE Customer Center password")
 
 	return installCmd
 }
 
-func checkParameters(cmd *cobra.Command, viper *viper.Viper, flags *flagpole, command string) {
-	utils.AskPasswordIfMissing(viper, "db.password", cmd.Flag("db-password").Usage)
+func checkParameters(cmd *cobra.Command, viper *viper.Viper, flags *flagpole, backend types.Backend) error {
+	if err := utils.AskPasswordIfMissing(viper, "db.password", cmd.Flag("db-password").Usage); err != nil {
+		return err
+	}
 
 	// Since we use cert-manager for self-signed certificates on kubernetes we don't need password for it
-	if !flags.cert.useExistingCertificate && command == "podman" {
-		utils.AskPasswordIfMissing(viper, "cert.password", cmd.Flag("cert-password").Usage)
+	if !viper.GetBool("cert.useexisting") && backend.Name() == podman.Name {
+		if err := utils.AskPasswordIfMissing(viper, "cert.password", cmd.Flag("cert-password").Usage); err != nil {
+			return err
+		}
 	}
 
 	// Use the host timezone if the user didn't define one
 	if viper.GetString("tz") == "" {
-		viper.Set("tz", utils.GetLocalTimezone())
+		timezone, err := utils.GetLocalTimezone()
+		if err != nil {
+			return err
+		}
+		viper.Set("tz", timezone)
 	}
 
 	if viper.GetString("email") == "" {
-		utils.AskIfMissing(viper, "email", cmd.Flag("email").Usage)
+		if err := utils.AskIfMissing(viper, "email", cmd.Flag("email").Usage); err != nil {
+			return err
+		}
 	}
 
 	if viper.GetString("fromEmail") == "" {
-		utils.AskIfMissing(viper, "emailfrom", cmd.Flag("emailfrom").Usage)
+		return utils.AskIfMissing(viper, "emailfrom", cmd.Flag("emailfrom").Usage)
 	}
+	return nil
 }
@@ -1,6 +1,7 @@
 package install
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
@@ -14,21 +15,30 @@ import (
 
 const SETUP_NAME = "setup.sh"
 
-func runSetup(viper *viper.Viper, globalFlags *types.GlobalFlags, fqdn string, env map[string]string) {
-	tmpFolder := generateSetupScript(viper, fqdn, env)
+func runSetup(viper *viper.Viper, globalFlags *types.GlobalFlags, backend types.Backend, fqdn string, env map[string]string) error {
+	tmpFolder, err := generateSetupScript(viper, fqdn, env)
+	if err != nil {
+		return err
+	}
 	defer os.RemoveAll(tmpFolder)
 
-	utils.Copy(globalFlags, filepath.Join(tmpFolder, SETUP_NAME), "server:/tmp/setup.sh", "root", "root")
+	if err := backend.Copy(globalFlags, filepath.Join(tmpFolder, SETUP_NAME), "server:/tmp/setup.sh", "root", "root"); err != nil {
+		return err
+	}
 
-	utils.Exec(globalFlags, false, false, []string{}, "/tmp/setup.sh")
+	if err := backend.Exec(globalFlags, false, false, []string{}, "/tmp/setup.sh"); err != nil {
+		return fmt.Errorf("failed to set up the server: %w", err)
+	}
 
 	log.Println("Server set up")
+	return nil
 }
 
 // generateSetupScript creates a temporary folder with the setup script to execute in the container.
 // The script exports all the needed environment variables and calls uyuni's mgr-setup.
 // Podman or kubernetes-specific variables can be passed using extraEnv parameter.
-func generateSetupScript(viper *viper.Viper, fqdn string, extraEnv map[string]string) string {
+// The folder is removed if the script can't be generated.
+func generateSetupScript(viper *viper.Viper, fqdn string, extraEnv map[string]string) (string, error) {
 	localHostValues := []string{
 		"localhost",
 		"127.0.0.1",
@@ -83,7 +93,7 @@ func generateSetupScript(viper *viper.Viper, fqdn string, extraEnv map[string]st
 
 	scriptDir, err := os.MkdirTemp("", "uyuniadm-*")
 	if err != nil {
-		log.Fatalf("Failed to create temporary directory: %s\n", err)
+		return "", fmt.Errorf("failed to create temporary directory: %w", err)
 	}
 
 	const scriptTemplate = `#!/bin/sh
@@ -102,18 +112,13 @@ rm $0`
 		Env: env,
 	}
 
-	file, err := os.OpenFile(filepath.Join(scriptDir, SETUP_NAME), os.O_WRONLY|os.O_CREATE, 0555)
-	if err != nil {
-		log.Fatalf("Fail to open setup script: %s\n", err)
-	}
-	defer file.Close()
-
 	t := template.Must(template.New("script").Parse(scriptTemplate))
-	if err = t.Execute(file, model); err != nil {
-		log.Fatalf("Failed to generate setup script: %s\n", err)
+	if err := utils.WriteTemplate(t, filepath.Join(scriptDir, SETUP_NAME), 0555, model); err != nil {
+		os.RemoveAll(scriptDir)
+		return "", fmt.Errorf("failed to generate setup script: %w", err)
 	}
 
-	return scriptDir
+	return scriptDir, nil
 }
 
 func boolToString(value bool) string {
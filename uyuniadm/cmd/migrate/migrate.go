@@ -2,18 +2,12 @@ package migrate
 
 import (
 	"github.com/spf13/cobra"
+	"github.com/uyuni-project/uyuni-tools/shared/backend"
 	"github.com/uyuni-project/uyuni-tools/shared/types"
 	"github.com/uyuni-project/uyuni-tools/shared/utils"
 )
 
-type flagpole struct {
-	Image    string
-	ImageTag string
-}
-
 func NewCommand(globalFlags *types.GlobalFlags) *cobra.Command {
-	flags := &flagpole{}
-
 	migrateCmd := &cobra.Command{
 		Use:   "migrate [source server FQDN]",
 		Short: "migrate a remote server to containers",
@@ -26,23 +20,47 @@ This migration command assumes a few things:
   * podman or kubectl is installed locally
   * if kubectl is installed, a working kubeconfig should be set to connect to the cluster to deploy to
 
+The migration is done in two phases:
+  * without --final, the data of the source server are synchronized while it is still running.
+    This pre-synchronization can be run several times to reduce the final phase duration.
+  * with --final, the source server services are stopped, the remaining changes and the database
+    are synchronized and the server container is started.
+
+If a phase is interrupted, running it again resumes the synchronization where it stopped.
+A final phase interrupted after the synchronization resumes with the server deployment.
+
+On kubernetes, the migration job runs on the local node to use the SSH agent: run uyuniadm on a cluster node.
+Use --timeout-migration to allow a longer synchronization.
+
 NOTE: for now installing on a remote cluster or podman is not supported yet!
 `,
 		Args: cobra.ExactArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
-			command := utils.GetCommand()
-			switch command {
-			case "podman":
-				migrateToPodman(globalFlags, flags, cmd, args)
-			case "kubectl":
-				migrateToKubernetes(globalFlags, flags, cmd, args)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			viper, err := utils.ReadConfig(globalFlags.ConfigPath, "admconfig", cmd)
+			if err != nil {
+				return err
+			}
+			b, err := backend.Get(globalFlags)
+			if err != nil {
+				return err
+			}
+			if !viper.GetBool("skip.checks") {
+				if err := utils.RunPreflightChecks(b.Check(viper, args[0])); err != nil {
+					return err
+				}
 			}
+			return b.Migrate(viper, globalFlags, args[0])
 		},
 	}
 
 	// TODO We probably want to move these default values to a config file
-	migrateCmd.Flags().StringVar(&flags.Image, "image", "registry.opensuse.org/uyuni/server", "Image")
-	migrateCmd.Flags().StringVar(&flags.ImageTag, "tag", "latest", "Tag Image")
+	migrateCmd.Flags().String("image", "registry.opensuse.org/uyuni/server", "Image")
+	migrateCmd.Flags().String("tag", "latest", "Tag Image")
+	migrateCmd.Flags().Bool("skip-checks", false, "Do not run the pre-flight checks")
+	migrateCmd.Flags().Bool("final", false, "Stop the source server, synchronize the remaining data and start the new server")
+	migrateCmd.Flags().String("state-dir", "", "Folder where to store the migration progress with podman. "+
+		"Defaults to /var/lib/uyuni-tools/migration for root and $XDG_STATE_HOME/uyuni-tools/migration for the other users. "+
+		"On kubernetes the progress is stored in the uyuni-migration-state persistent volume claim")
 
 	return migrateCmd
 }
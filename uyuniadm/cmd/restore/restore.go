@@ -0,0 +1,55 @@
+package restore
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/uyuni-project/uyuni-tools/shared/backend"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+type flagpole struct {
+	Force  bool
+	TmpDir string
+}
+
+func NewCommand(globalFlags *types.GlobalFlags) *cobra.Command {
+	flags := &flagpole{}
+
+	restoreCmd := &cobra.Command{
+		Use:   "restore [path/to/archive]",
+		Short: "restore a server from a backup archive",
+		Long: `Restore a server from a backup archive created by the backup command
+
+The checksums of the archive files are verified first and the target image must not be older
+than the backed up server. If the target image is more recent, the database schema is upgraded.
+
+The server volumes are created from the archive content and the server is deployed:
+  * on podman, the uyuni-server systemd service is generated and started,
+  * on kubernetes, the persistent volume claims are created and the uyuni helm chart is deployed.
+
+Existing volumes with data are only replaced if --force is passed. The existing server is then removed first:
+  * on podman, the uyuni-server systemd service is stopped and removed,
+  * on kubernetes, the uyuni helm release is uninstalled.
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			viper, err := utils.ReadConfig(globalFlags.ConfigPath, "admconfig", cmd)
+			if err != nil {
+				return err
+			}
+			b, err := backend.Get(globalFlags)
+			if err != nil {
+				return err
+			}
+			return runRestore(viper, globalFlags, b, flags, args[0])
+		},
+	}
+
+	restoreCmd.Flags().String("image", "", "Image. Defaults to the image of the backed up server")
+	restoreCmd.Flags().String("tag", "", "Tag Image. Defaults to the tag of the backed up server")
+	restoreCmd.Flags().String("tz", "", "Time zone to set on the server. Defaults to the backed up server one")
+	restoreCmd.Flags().BoolVar(&flags.Force, "force", false, "Replace the existing volumes even if they contain data")
+	restoreCmd.Flags().StringVar(&flags.TmpDir, "tmp-dir", "", "Folder where to store the temporary files. Defaults to the archive folder")
+
+	return restoreCmd
+}
@@ -0,0 +1,256 @@
+package restore
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+func runRestore(viper *viper.Viper, globalFlags *types.GlobalFlags, backend types.Backend, flags *flagpole, archivePath string) error {
+	manifest, err := readManifest(archivePath)
+	if err != nil {
+		return err
+	}
+
+	// Use the backed up server values if not defined by the user
+	image, tag := splitImage(manifest.Image)
+	if viper.GetString("image") == "" {
+		viper.Set("image", image)
+	}
+	if viper.GetString("tag") == "" {
+		viper.Set("tag", tag)
+	}
+	if viper.GetString("tz") == "" {
+		viper.Set("tz", manifest.Timezone)
+	}
+
+	// Check the target image can run the backed up data
+	targetImage := fmt.Sprintf("%s:%s", viper.GetString("image"), viper.GetString("tag"))
+	targetRelease, err := backend.GetImageRelease(viper, targetImage)
+	if err != nil {
+		return err
+	}
+	targetVersion := utils.ParseServerVersion(targetRelease)
+	if targetVersion == "" {
+		return fmt.Errorf("failed to find the server version in image %s", targetImage)
+	}
+	if utils.CompareVersions(targetVersion, manifest.Version) < 0 {
+		return fmt.Errorf("cannot restore a version %s backup using the older %s image version %s",
+			manifest.Version, targetImage, targetVersion)
+	}
+
+	tmpDir := flags.TmpDir
+	if tmpDir == "" {
+		tmpDir = filepath.Dir(archivePath)
+	}
+	workDir, err := os.MkdirTemp(tmpDir, "uyuniadm-restore-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	if err := backend.CreateVolumes(viper, globalFlags, flags.Force); err != nil {
+		return err
+	}
+
+	// The previous server data is gone: the only way out is to restore again
+	if err := restoreServer(viper, globalFlags, backend, archivePath, manifest, targetVersion, workDir); err != nil {
+		return fmt.Errorf("%w\nthe server volumes have been replaced but the server is only partially restored: "+
+			"fix the problem and run the restore again with --force", err)
+	}
+
+	log.Printf("Server restored from %s\n", archivePath)
+	return nil
+}
+
+// restoreServer fills the created volumes with the archive content, deploys the server and restores its database.
+// The failed server services are only reported once the database is restored: they can't work without it.
+func restoreServer(viper *viper.Viper, globalFlags *types.GlobalFlags, backend types.Backend, archivePath string,
+	manifest *types.BackupManifest, targetVersion string, workDir string) error {
+	if err := extractArchive(viper, globalFlags, backend, archivePath, manifest, workDir); err != nil {
+		return err
+	}
+
+	if err := backend.Deploy(viper, globalFlags, manifest.Fqdn); err != nil {
+		return err
+	}
+
+	if err := restoreDatabase(globalFlags, backend, filepath.Join(workDir, manifest.Database.File),
+		filepath.Join(workDir, manifest.DatabaseConfig.File)); err != nil {
+		return err
+	}
+
+	if utils.CompareVersions(targetVersion, manifest.Version) > 0 {
+		log.Printf("Upgrading the database schema from version %s to %s\n", manifest.Version, targetVersion)
+		if err := utils.RunServerCmd(backend, utils.SchemaUpgradeCommand, nil, "failed to upgrade the database schema"); err != nil {
+			return err
+		}
+	} else if err := utils.RunServerCmd(backend, "spacewalk-service restart", nil, "failed to restart the services"); err != nil {
+		return err
+	}
+	return backend.WaitReady()
+}
+
+// extractArchive imports the volumes of the archive and saves the database files in workDir.
+func extractArchive(viper *viper.Viper, globalFlags *types.GlobalFlags, backend types.Backend, archivePath string,
+	manifest *types.BackupManifest, workDir string) error {
+	volumeFiles := map[string]string{}
+	for name, entry := range manifest.Volumes {
+		volumeFiles[entry.File] = name
+	}
+
+	archive, err := utils.OpenArchive(archivePath)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s archive: %w", archivePath, err)
+		}
+
+		switch header.Name {
+		case manifest.Database.File, manifest.DatabaseConfig.File:
+			// The database can only be restored once the server is running
+			if err := saveFile(archive, filepath.Join(workDir, header.Name)); err != nil {
+				return err
+			}
+		default:
+			if name, ok := volumeFiles[header.Name]; ok {
+				log.Printf("Restoring volume %s\n", name)
+				if err := backend.ImportVolume(viper, globalFlags, name, archive); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// readManifest reads the manifest of a backup archive and checks the checksums of the files it lists.
+func readManifest(archivePath string) (*types.BackupManifest, error) {
+	log.Printf("Verifying %s archive\n", archivePath)
+	archive, err := utils.OpenArchive(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	checksums := map[string]string{}
+	var content []byte
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s archive: %w", archivePath, err)
+		}
+
+		if header.Name == types.BackupManifestName {
+			if content, err = io.ReadAll(archive); err != nil {
+				return nil, fmt.Errorf("failed to read the backup manifest: %w", err)
+			}
+			continue
+		}
+
+		hash := sha256.New()
+		if _, err := io.Copy(hash, archive); err != nil {
+			return nil, fmt.Errorf("failed to read %s from the archive: %w", header.Name, err)
+		}
+		checksums[header.Name] = fmt.Sprintf("%x", hash.Sum(nil))
+	}
+
+	if content == nil {
+		return nil, fmt.Errorf("no %s file in %s, this is not a backup archive", types.BackupManifestName, archivePath)
+	}
+	var manifest types.BackupManifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse the backup manifest: %w", err)
+	}
+
+	entries := []types.BackupEntry{manifest.Database, manifest.DatabaseConfig}
+	for _, entry := range manifest.Volumes {
+		entries = append(entries, entry)
+	}
+	for _, entry := range entries {
+		checksum, ok := checksums[entry.File]
+		if !ok {
+			return nil, fmt.Errorf("file %s is missing in the archive", entry.File)
+		}
+		if checksum != entry.Checksum {
+			return nil, fmt.Errorf("invalid checksum for %s: the archive is corrupted", entry.File)
+		}
+	}
+
+	log.Printf("Archive of %s server version %s created on %s\n", manifest.Fqdn, manifest.Version, manifest.Date)
+	return &manifest, nil
+}
+
+// restoreDatabase loads the database configuration and dump into the PostgreSQL server of the container.
+func restoreDatabase(globalFlags *types.GlobalFlags, backend types.Backend, dumpPath string, configPath string) error {
+	log.Println("Restoring the database")
+	if err := utils.RunServerCmd(backend, "spacewalk-service stop && systemctl stop postgresql", nil,
+		"failed to stop the services"); err != nil {
+		return err
+	}
+	if err := utils.RunServerCmd(backend, "test -f /var/lib/pgsql/data/PG_VERSION || su - postgres -c 'initdb -D /var/lib/pgsql/data'",
+		nil, "failed to initialize the database"); err != nil {
+		return err
+	}
+
+	config, err := os.Open(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", configPath, err)
+	}
+	defer config.Close()
+	if err := utils.RunServerCmd(backend, "tar -C /var/lib/pgsql/data -xf - && chown -R postgres:postgres /var/lib/pgsql/data",
+		config, "failed to restore the database configuration"); err != nil {
+		return err
+	}
+
+	if err := utils.RunServerCmd(backend, "systemctl start postgresql", nil, "failed to start the database"); err != nil {
+		return err
+	}
+
+	dump, err := os.Open(dumpPath)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", dumpPath, err)
+	}
+	defer dump.Close()
+	return utils.RunServerCmd(backend, "su - postgres -c 'psql -q -d postgres'", dump, "failed to restore the database dump")
+}
+
+func saveFile(content io.Reader, path string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer file.Close()
+	if _, err = io.Copy(file, content); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// splitImage returns the image name and tag of a full image reference.
+func splitImage(fullImage string) (string, string) {
+	index := strings.LastIndex(fullImage, ":")
+	if index < 0 || strings.Contains(fullImage[index:], "/") {
+		return fullImage, "latest"
+	}
+	return fullImage[:index], fullImage[index+1:]
+}
@@ -0,0 +1,126 @@
+package restore
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/uyuni-project/uyuni-tools/shared/podman"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// testBackend is a podman backend deploying the server without generating its systemd units.
+type testBackend struct {
+	types.Backend
+}
+
+// Deploy waits for the server like the podman backend once its service is started.
+func (b testBackend) Deploy(viper *viper.Viper, globalFlags *types.GlobalFlags, fqdn string) error {
+	return utils.WaitForServer(b, time.Second, false)
+}
+
+func TestRestoreServer(t *testing.T) {
+	running := utils.FakeResult{Prefix: "podman ps -q", Output: "0123456789ab\n"}
+	tests := []struct {
+		name        string
+		results     []utils.FakeResult
+		expectedErr bool
+		waited      bool
+	}{
+		{
+			name:    "restored",
+			results: []utils.FakeResult{running},
+			waited:  true,
+		},
+		{
+			name: "failed services after the database restore",
+			results: []utils.FakeResult{
+				running,
+				{Prefix: "podman exec -i uyuni-server systemctl list-units", Output: "tomcat.service loaded failed failed Tomcat\n"},
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			runner := &utils.FakeRunner{Results: test.results}
+			previousRunner := utils.SetRunner(runner)
+			defer utils.SetRunner(previousRunner)
+
+			dir := t.TempDir()
+			archivePath := filepath.Join(dir, "backup.tar")
+			manifest := writeTestArchive(t, archivePath)
+
+			globalFlags := types.GlobalFlags{Timeouts: types.Timeouts{ServerStart: time.Second}}
+			backend := testBackend{Backend: podman.NewBackend(&globalFlags)}
+
+			err := restoreServer(viper.New(), &globalFlags, backend, archivePath, manifest, manifest.Version, dir)
+			if test.expectedErr && err == nil {
+				t.Error("expected an error")
+			} else if !test.expectedErr && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+
+			// The failed services are only checked once the database is restored and the services restarted
+			serverCmd := func(command string) []string {
+				return []string{"podman ps -q -f name=^uyuni-server$", "podman exec -i uyuni-server " + command}
+			}
+			expected := []string{"podman volume import etc-rhn -"}
+			expected = append(expected, serverCmd("systemctl is-active -q multi-user.target")...)
+			for _, command := range []string{
+				"spacewalk-service stop && systemctl stop postgresql",
+				"test -f /var/lib/pgsql/data/PG_VERSION || su - postgres -c 'initdb -D /var/lib/pgsql/data'",
+				"tar -C /var/lib/pgsql/data -xf - && chown -R postgres:postgres /var/lib/pgsql/data",
+				"systemctl start postgresql",
+				"su - postgres -c 'psql -q -d postgres'",
+				"spacewalk-service restart",
+			} {
+				expected = append(expected, serverCmd("sh -c "+command)...)
+			}
+			expected = append(expected, serverCmd("systemctl list-units --state=failed --no-legend --plain "+
+				strings.Join(utils.ServerServices, " "))...)
+			if test.waited {
+				expected = append(expected, serverCmd("systemctl is-active -q multi-user.target")...)
+			}
+
+			if len(runner.Commands) != len(expected) {
+				t.Fatalf("expected %d commands, got %d:\n  %s", len(expected), len(runner.Commands),
+					strings.Join(runner.Commands, "\n  "))
+			}
+			for i, command := range runner.Commands {
+				if command != expected[i] {
+					t.Errorf("command %d: expected %q, got %q", i, expected[i], command)
+				}
+			}
+		})
+	}
+}
+
+// writeTestArchive writes a backup archive with a database and a volume at path and returns its manifest.
+func writeTestArchive(t *testing.T, path string) *types.BackupManifest {
+	archive, err := utils.CreateArchive(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := types.BackupManifest{
+		Image:          "registry.example.com/uyuni/server:test",
+		Version:        "2024.05",
+		Fqdn:           "server.example.com",
+		Database:       types.BackupEntry{File: "database.sql"},
+		DatabaseConfig: types.BackupEntry{File: "database-config.tar"},
+		Volumes:        map[string]types.BackupEntry{"etc-rhn": {File: "volumes/etc-rhn.tar", Path: "/etc/rhn"}},
+	}
+	for _, file := range []string{"database.sql", "database-config.tar", "volumes/etc-rhn.tar"} {
+		if err := archive.AddContent(file, []byte(file)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &manifest
+}
@@ -2,8 +2,8 @@ package uninstall
 
 import (
 	"github.com/spf13/cobra"
+	"github.com/uyuni-project/uyuni-tools/shared/backend"
 	"github.com/uyuni-project/uyuni-tools/shared/types"
-	"github.com/uyuni-project/uyuni-tools/shared/utils"
 )
 
 func NewCommand(globalFlags *types.GlobalFlags) *cobra.Command {
@@ -11,20 +11,16 @@ func NewCommand(globalFlags *types.GlobalFlags) *cobra.Command {
 		Use:   "uninstall",
 		Short: "uninstall a server",
 		Args:  cobra.ExactArgs(0),
-		Run: func(cmd *cobra.Command, args []string) {
-			dryRun, _ := cmd.Flags().GetBool("dry-run")
+		RunE: func(cmd *cobra.Command, args []string) error {
 			purge, _ := cmd.Flags().GetBool("purge-volumes")
 
-			command := utils.GetCommand()
-			switch command {
-			case "podman":
-				uninstallForPodman(globalFlags, dryRun, purge)
-			case "kubectl":
-				uninstallForKubernetes(globalFlags, dryRun)
+			b, err := backend.Get(globalFlags)
+			if err != nil {
+				return err
 			}
+			return b.Uninstall(globalFlags, globalFlags.DryRun, purge)
 		},
 	}
-	uninstallCmd.Flags().BoolP("dry-run", "n", false, "Only show what would be done")
 	uninstallCmd.Flags().Bool("purge-volumes", false, "Also remove the volume (podman only)")
 
 	return uninstallCmd
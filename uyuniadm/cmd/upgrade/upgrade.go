@@ -0,0 +1,48 @@
+package upgrade
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/uyuni-project/uyuni-tools/shared/backend"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+func NewCommand(globalFlags *types.GlobalFlags) *cobra.Command {
+	upgradeCmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "upgrade a running server to a new image",
+		Long: `Upgrade a running server to a new image
+
+The upgrade command pulls the new image, checks it is not older than the running one,
+restarts the server with it and upgrades the database schema.
+
+On podman, the image is changed in the uyuni-server systemd service unit.
+On kubernetes, the uyuni helm release is upgraded reusing its previous values.
+
+If the upgraded server isn't healthy within the rollback timeout, the previous state is restored:
+  * on podman, the volumes are restored from snapshots taken before the upgrade and the previous image is used,
+  * on kubernetes, the uyuni helm release is rolled back to its previous revision.
+    The persistent volumes are not restored: if the database schema upgrade failed, restore a backup.
+`,
+		Args: cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			viper, err := utils.ReadConfig(globalFlags.ConfigPath, "admconfig", cmd)
+			if err != nil {
+				return err
+			}
+			b, err := backend.Get(globalFlags)
+			if err != nil {
+				return err
+			}
+			return b.Upgrade(viper, globalFlags)
+		},
+	}
+
+	upgradeCmd.Flags().String("image", "registry.opensuse.org/uyuni/server", "Image")
+	upgradeCmd.Flags().String("tag", "latest", "Tag Image")
+	upgradeCmd.Flags().Int("rollback-timeout", 300, "Seconds to wait for the upgraded server to be healthy before rolling back")
+	upgradeCmd.Flags().String("snapshot-dir", "", "Folder where to save the podman volumes before upgrading. "+
+		"Defaults to /var/lib/uyuni-tools/snapshots for root and $XDG_STATE_HOME/uyuni-tools/snapshots for the other users")
+
+	return upgradeCmd
+}
@@ -1,10 +1,17 @@
 package cmd
 
 import (
+	"strings"
+
 	"github.com/spf13/cobra"
+	"github.com/uyuni-project/uyuni-tools/shared/backend"
+	"github.com/uyuni-project/uyuni-tools/shared/kubernetes"
 	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
 	"github.com/uyuni-project/uyuni-tools/uyunictl/cmd/cp"
 	"github.com/uyuni-project/uyuni-tools/uyunictl/cmd/exec"
+	"github.com/uyuni-project/uyuni-tools/uyunictl/cmd/logs"
+	"github.com/uyuni-project/uyuni-tools/uyunictl/cmd/status"
 )
 
 // NewCommand returns a new cobra.Command implementing the root command for kinder
@@ -15,14 +22,43 @@ func NewUyunictlCommand() *cobra.Command {
 		Short:   "Uyuni control tool",
 		Long:    "Uyuni control tool used to help user managing Uyuni and SUSE Manager Servers mainly through its API",
 		Version: "0.0.1",
+		// The errors are printed by main with the matching exit code
+		SilenceUsage:  true,
+		SilenceErrors: true,
 	}
 
 	rootCmd.PersistentFlags().BoolVarP(&globalFlags.Verbose, "verbose", "v", false, "verbose output")
+	rootCmd.PersistentFlags().StringVarP(&globalFlags.ConfigPath, "config", "c", "", "configuration file path")
+	rootCmd.PersistentFlags().StringVar(&globalFlags.Backend, "backend", backend.Auto,
+		"tool to use to manage the server: "+strings.Join(backend.Names(), ", ")+" or "+backend.Auto)
+	rootCmd.PersistentFlags().StringVar(&globalFlags.Namespace, "namespace", kubernetes.DefaultNamespace,
+		"kubernetes namespace of the server")
+	rootCmd.PersistentFlags().StringVar(&globalFlags.Instance, "instance", "",
+		"name of the server instance on a podman host running several servers")
+	rootCmd.PersistentFlags().BoolVar(&globalFlags.Rootless, "rootless", false,
+		"manage a server running in rootless podman of the current user")
+	rootCmd.PersistentFlags().StringVar(&globalFlags.KubeContext, "kube-context", "",
+		"kubeconfig context of the cluster running the server, the current context by default")
 
-	// TODO Add --namespace parameter for kubernetes ?
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		// The backend and namespace can also be set in the configuration file or UYUNI_* environment variables
+		viper, err := utils.ReadConfig(globalFlags.ConfigPath, "ctlconfig", cmd)
+		if err != nil {
+			return err
+		}
+		globalFlags.Backend = viper.GetString("backend")
+		globalFlags.Namespace = viper.GetString("namespace")
+		globalFlags.Instance = viper.GetString("instance")
+		globalFlags.Rootless = viper.GetBool("rootless")
+		globalFlags.KubeContext = viper.GetString("kube.context")
+		utils.SetRunner(utils.NewRunner(globalFlags))
+		return nil
+	}
 
 	rootCmd.AddCommand(exec.NewCommand(globalFlags))
 	rootCmd.AddCommand(cp.NewCommand(globalFlags))
+	rootCmd.AddCommand(status.NewCommand(globalFlags))
+	rootCmd.AddCommand(logs.NewCommand(globalFlags))
 
 	return rootCmd
 }
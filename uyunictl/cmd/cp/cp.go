@@ -2,8 +2,8 @@ package cp
 
 import (
 	"github.com/spf13/cobra"
+	"github.com/uyuni-project/uyuni-tools/shared/backend"
 	"github.com/uyuni-project/uyuni-tools/shared/types"
-	"github.com/uyuni-project/uyuni-tools/shared/utils"
 )
 
 type flagpole struct {
@@ -20,8 +20,8 @@ func NewCommand(globalFlags *types.GlobalFlags) *cobra.Command {
 		Long: `copy takes a source and destination parameters.
 	One of them can be prefixed with 'server:' to indicate the path is within the server pod.`,
 		Args: cobra.ExactArgs(2),
-		Run: func(cmd *cobra.Command, args []string) {
-			run(globalFlags, flags, cmd, args)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return run(globalFlags, flags, cmd, args)
 		},
 	}
 
@@ -30,6 +30,10 @@ func NewCommand(globalFlags *types.GlobalFlags) *cobra.Command {
 	return cpCmd
 }
 
-func run(globalFlags *types.GlobalFlags, flags *flagpole, cmd *cobra.Command, args []string) {
-	utils.Copy(globalFlags, args[0], args[1], flags.User, flags.Group)
+func run(globalFlags *types.GlobalFlags, flags *flagpole, cmd *cobra.Command, args []string) error {
+	b, err := backend.Get(globalFlags)
+	if err != nil {
+		return err
+	}
+	return b.Copy(globalFlags, args[0], args[1], flags.User, flags.Group)
 }
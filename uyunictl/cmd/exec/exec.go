@@ -2,8 +2,8 @@ package exec
 
 import (
 	"github.com/spf13/cobra"
+	"github.com/uyuni-project/uyuni-tools/shared/backend"
 	"github.com/uyuni-project/uyuni-tools/shared/types"
-	"github.com/uyuni-project/uyuni-tools/shared/utils"
 )
 
 type flagpole struct {
@@ -19,8 +19,8 @@ func NewCommand(globalFlags *types.GlobalFlags) *cobra.Command {
 	execCmd := &cobra.Command{
 		Use:   "exec '[command-to-run --with-args]'",
 		Short: "execute commands inside the uyuni containers using 'sh -c'",
-		Run: func(cmd *cobra.Command, args []string) {
-			run(globalFlags, flags, cmd, args)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return run(globalFlags, flags, cmd, args)
 		},
 	}
 	execCmd.Flags().StringArrayVarP(&flags.Envs, "env", "e", []string{}, "environment variables to pass to the command")
@@ -29,6 +29,10 @@ func NewCommand(globalFlags *types.GlobalFlags) *cobra.Command {
 	return execCmd
 }
 
-func run(globalFlags *types.GlobalFlags, flags *flagpole, cmd *cobra.Command, args []string) {
-	utils.Exec(globalFlags, flags.Interactive, flags.Tty, flags.Envs, args...)
+func run(globalFlags *types.GlobalFlags, flags *flagpole, cmd *cobra.Command, args []string) error {
+	b, err := backend.Get(globalFlags)
+	if err != nil {
+		return err
+	}
+	return b.Exec(globalFlags, flags.Interactive, flags.Tty, flags.Envs, args...)
 }
@@ -0,0 +1,62 @@
+package logs
+
+import (
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/uyuni-project/uyuni-tools/shared/backend"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+type flagpole struct {
+	Follow bool
+	Since  string
+	Tail   int
+}
+
+func NewCommand(globalFlags *types.GlobalFlags) *cobra.Command {
+	flags := &flagpole{}
+
+	logsCmd := &cobra.Command{
+		Use:   "logs [component...]",
+		Short: "show the server logs",
+		Long: `Show the server logs
+
+Without component, the output of the server container is shown.
+Otherwise the logs of the components are read from the journal and the log files of the server,
+and merged by their timestamps. The possible components are: ` + strings.Join(utils.LogComponentNames(), ", ") + `.
+
+When following several components, the lines are shown as they are written
+and --tail applies to each journal and log file.
+`,
+		ValidArgs: utils.LogComponentNames(),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return run(globalFlags, flags, args)
+		},
+	}
+
+	logsCmd.Flags().BoolVarP(&flags.Follow, "follow", "f", false, "Keep showing the new log lines")
+	logsCmd.Flags().StringVar(&flags.Since, "since", "",
+		"Only show the lines written since a duration like 10m or a RFC 3339 time like 2006-01-02T15:04:05Z")
+	logsCmd.Flags().IntVar(&flags.Tail, "tail", -1, "Number of lines to show from the end of the logs, all of them if negative")
+	return logsCmd
+}
+
+func run(globalFlags *types.GlobalFlags, flags *flagpole, args []string) error {
+	since, err := utils.ParseLogsSince(flags.Since)
+	if err != nil {
+		return err
+	}
+	options := types.LogsOptions{Follow: flags.Follow, Since: since, Tail: flags.Tail}
+
+	b, err := backend.Get(globalFlags)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return b.Logs(globalFlags, options)
+	}
+	return utils.ShowComponentLogs(b, args, options, os.Stdout)
+}
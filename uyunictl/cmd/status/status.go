@@ -0,0 +1,67 @@
+package status
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/uyuni-project/uyuni-tools/shared/backend"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+type flagpole struct {
+	Output string
+}
+
+func NewCommand(globalFlags *types.GlobalFlags) *cobra.Command {
+	flags := &flagpole{}
+
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "show the health of the server",
+		Long: `Show the health of the server
+
+The status contains the backend, the state of the server container or pod, its image, tag and uptime,
+the state of the main services, the usage of the volumes and the expiration of the certificates.
+
+The command exits with:
+  * 0 if the server is healthy,
+  * 3 if the server is not running,
+  * 4 if the server is degraded: a service is not active, a volume is more than 90% full
+    or a certificate expires in less than 30 days.
+`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return run(globalFlags, flags, cmd)
+		},
+	}
+
+	statusCmd.Flags().StringVarP(&flags.Output, "output", "o", "table", "Output format: table, json or yaml")
+	return statusCmd
+}
+
+func run(globalFlags *types.GlobalFlags, flags *flagpole, cmd *cobra.Command) error {
+	viper, err := utils.ReadConfig(globalFlags.ConfigPath, "ctlconfig", cmd)
+	if err != nil {
+		return err
+	}
+	b, err := backend.Get(globalFlags)
+	if err != nil {
+		return err
+	}
+
+	status, statusErr := utils.GetServerStatus(b, viper)
+	if err := utils.PrintServerStatus(status, flags.Output, os.Stdout); err != nil {
+		return err
+	}
+	if statusErr != nil {
+		return statusErr
+	}
+	if len(status.Problems) > 0 {
+		return &utils.DegradedError{Problems: status.Problems}
+	}
+	if globalFlags.Verbose && flags.Output == "table" {
+		return b.Status(globalFlags)
+	}
+	return nil
+}
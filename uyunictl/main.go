@@ -1,8 +1,10 @@
 package main
 
 import (
+	"log"
 	"os"
 
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
 	"github.com/uyuni-project/uyuni-tools/uyunictl/cmd"
 )
 
@@ -13,6 +15,9 @@ func Run() error {
 
 func main() {
 	if err := Run(); err != nil {
-		os.Exit(1)
+		if !utils.IsSilentError(err) {
+			log.Println(err)
+		}
+		os.Exit(utils.GetExitCode(err))
 	}
 }